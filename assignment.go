@@ -0,0 +1,310 @@
+// assignment.go - DIMACS "p asn" assignment problems and bipartite matching.
+// See: http://lpsolve.sourceforge.net/5.5/DIMACS_asn.htm
+
+package pseudo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// AssignmentProblem is a bipartite graph whose source side nodes, those
+// listed on "n" lines of DIMACS input, are each to be assigned to a distinct
+// node of the other side along an arc, at the least total cost.
+type AssignmentProblem struct {
+	numNodes uint
+	left     []bool
+	arcs     []AssignmentArc
+}
+
+// AssignmentArc is an arc of an AssignmentProblem.
+type AssignmentArc struct {
+	ID       ArcID
+	From, To uint
+	Cost     int64
+}
+
+// Assignment is a solution of an AssignmentProblem.
+type Assignment struct {
+	// Cost is the sum of the costs of Arcs.
+	Cost int64
+	// Arcs are the arcs of the assignment in ArcID order.
+	Arcs []AssignmentArc
+}
+
+// NewAssignmentProblem returns an AssignmentProblem with nodes numbered
+// 1 to numNodes, all on the sink side, and no arcs.
+func NewAssignmentProblem(numNodes uint) *AssignmentProblem {
+	return &AssignmentProblem{
+		numNodes: numNodes,
+		left:     make([]bool, numNodes),
+	}
+}
+
+// SetSource puts node n on the source side. It panics if n is not in the problem.
+func (p *AssignmentProblem) SetSource(n uint) {
+	p.checkNode("SetSource", n)
+	p.left[n-1] = true
+}
+
+// AddArc adds an arc from node 'from' to node 'to' with the given cost and
+// returns its ArcID. It panics if either node is not in the problem.
+// Arcs must run from the source side to the sink side, which is checked
+// when the problem is solved.
+func (p *AssignmentProblem) AddArc(from, to uint, cost int64) ArcID {
+	p.checkNode("AddArc", from)
+	p.checkNode("AddArc", to)
+	id := ArcID(len(p.arcs))
+	p.arcs = append(p.arcs, AssignmentArc{ID: id, From: from, To: to, Cost: cost})
+	return id
+}
+
+func (p *AssignmentProblem) checkNode(fn string, n uint) {
+	if n < 1 || n > p.numNodes {
+		panic(fmt.Sprintf("pseudo: %s(%d): node out of range 1..%d", fn, n, p.numNodes))
+	}
+}
+
+// check returns an error if an arc does not run from the source side to the sink side.
+func (p *AssignmentProblem) check() error {
+	for _, a := range p.arcs {
+		if !p.left[a.From-1] || p.left[a.To-1] {
+			return fmt.Errorf("arc %d (%d, %d) does not run from the source side to the sink side",
+				a.ID, a.From, a.To)
+		}
+	}
+	return nil
+}
+
+// ReadAssignment reads a DIMACS "p asn" problem from r, which may be
+// compressed with gzip or bzip2. Input that is not a valid "p asn" problem
+// returns a *ParseError, as for ReadDimacs. maxNodes limits its nodes as
+// for ReadMinCost.
+//
+// Example:
+//
+//	p asn 6 7
+//	n 1
+//	n 2
+//	n 3
+//	a 1 4 5
+//	...
+func ReadAssignment(r io.Reader, maxNodes uint) (*AssignmentProblem, error) {
+	if maxNodes == 0 {
+		maxNodes = DefaultMaxNodes
+	}
+	var p *AssignmentProblem
+	var numArcs uint
+	d, err := newDimacsReader(r)
+	if err != nil {
+		return nil, err
+	}
+	l := &d.l
+	for {
+		f, col, err := d.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if f[0] != 'p' && p == nil {
+			return nil, l.errorf(col, "%q line before problem line", f)
+		}
+
+		switch f[0] {
+		case 'p':
+			if p != nil {
+				return nil, l.errorf(col, "second problem line")
+			}
+			n, _, arcs, err := l.problem("asn", maxNodes)
+			if err != nil {
+				return nil, err
+			}
+			p, numArcs = NewAssignmentProblem(n), arcs
+		case 'n':
+			i, err := l.node("node", p.numNodes)
+			if err != nil {
+				return nil, err
+			}
+			if err := l.end(); err != nil {
+				return nil, err
+			}
+			p.left[i-1] = true
+		case 'a':
+			if uint(len(p.arcs)) == numArcs {
+				return nil, l.errorf(col, "more arc lines than the %d of the problem line", numArcs)
+			}
+			from, err := l.node("from node", p.numNodes)
+			if err != nil {
+				return nil, err
+			}
+			to, err := l.node("to node", p.numNodes)
+			if err != nil {
+				return nil, err
+			}
+			cost, err := l.integer("cost")
+			if err != nil {
+				return nil, err
+			}
+			if err := l.end(); err != nil {
+				return nil, err
+			}
+			p.AddArc(from, to, cost)
+		default:
+			return nil, l.errorf(col, "unknown line type %q", f)
+		}
+	}
+	if p == nil {
+		return nil, d.endError("no problem line")
+	}
+	if uint(len(p.arcs)) != numArcs {
+		return nil, d.endError("%d arc lines, the problem line has %d", len(p.arcs), numArcs)
+	}
+
+	return p, nil
+}
+
+// SolveAssignment reads a "p asn" problem from the input file and solves it.
+// If input == "stdin" then os.Stdin is read.
+func SolveAssignment(input string) (*Assignment, error) {
+	var r io.Reader = os.Stdin
+	if strings.ToLower(input) != "stdin" {
+		fh, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		defer fh.Close()
+		r = fh
+	}
+
+	p, err := ReadAssignment(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	return p.Solve()
+}
+
+// Solve returns a least cost assignment of every source side node, or an
+// error if there is none. It is solved as a MinCostNetwork in which each
+// source side node supplies one unit and an extra node collects one unit
+// from each sink side node.
+func (p *AssignmentProblem) Solve() (*Assignment, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+
+	sink := p.numNodes + 1
+	m := NewMinCostNetwork(sink)
+	var numLeft int64
+	for i, left := range p.left {
+		if left {
+			m.SetSupply(uint(i+1), 1)
+			numLeft++
+		}
+	}
+	m.SetSupply(sink, -numLeft)
+	for _, a := range p.arcs {
+		m.AddArc(a.From, a.To, 0, 1, a.Cost)
+	}
+	for i, left := range p.left {
+		if !left {
+			m.AddArc(uint(i+1), sink, 0, 1, 0)
+		}
+	}
+
+	sol, err := m.Solve()
+	if err != nil {
+		return nil, fmt.Errorf("no complete assignment: %v", err)
+	}
+	asn := new(Assignment)
+	for i, a := range p.arcs {
+		if sol.Arcs[i].Flow > 0 {
+			asn.Arcs = append(asn.Arcs, a)
+			asn.Cost += a.Cost
+		}
+	}
+
+	return asn, nil
+}
+
+// MaxMatching returns a maximum cardinality matching of the problem,
+// ignoring arc costs; Cost is the cost of the arcs it happens to use.
+// It is found as a maximum flow with the pseudoflow algorithm, on a Network
+// with Context PseudoCtx that has an arc of capacity 1 from an extra source
+// to each source side node, from each sink side node to an extra sink, and
+// for each arc of the problem.
+func (p *AssignmentProblem) MaxMatching() (*Assignment, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+
+	source, sink := p.numNodes+1, p.numNodes+2
+	nw := NewNetwork(p.numNodes + 2)
+	nw.SetSource(source)
+	nw.SetSink(sink)
+	ids := make([]ArcID, len(p.arcs))
+	for i, a := range p.arcs {
+		ids[i] = nw.AddArc(a.From, a.To, 1)
+	}
+	for i, left := range p.left {
+		if left {
+			nw.AddArc(source, uint(i+1), 1)
+		} else {
+			nw.AddArc(uint(i+1), sink, 1)
+		}
+	}
+
+	sol, err := nw.Solve()
+	if err != nil {
+		return nil, err
+	}
+	flow := make([]int64, len(sol.Arcs))
+	for _, a := range sol.Arcs {
+		flow[a.ID] = a.Flow
+	}
+	asn := new(Assignment)
+	for i, a := range p.arcs {
+		if flow[ids[i]] > 0 {
+			asn.Arcs = append(asn.Arcs, a)
+			asn.Cost += a.Cost
+		}
+	}
+
+	return asn, nil
+}
+
+// Dimacs returns the Assignment in DIMACS syntax: the cost on an "s" line
+// and each assigned arc on an "f" line with a flow of 1.
+//
+// Example for examples/dimacsAsn.txt:
+//
+//	c <header>
+//	c
+//	c Dimacs-format assignment result file
+//	c generated by pseudo.go
+//	c
+//	s 6
+//	c
+//	c SRC DST FLOW
+//	f 1 5 1
+//	...
+func (a *Assignment) Dimacs(header string) []string {
+	ret := []string{
+		"c " + header,
+		"c ",
+		"c Dimacs-format assignment result file",
+		"c generated by pseudo.go",
+		"c ",
+		fmt.Sprintf("s %d", a.Cost),
+		"c ",
+		"c SRC DST FLOW",
+	}
+	for _, arc := range a.Arcs {
+		ret = append(ret, fmt.Sprintf("f %d %d 1", arc.From, arc.To))
+	}
+
+	return ret
+}
@@ -0,0 +1,257 @@
+// binary.go - save and load a Network in a compact binary format.
+
+package pseudo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"time"
+)
+
+// The binary format of a network, all numbers little-endian:
+//
+//	magic     [4]byte  "PSDO"
+//	version   uint16   BinaryVersion
+//	flags     uint16   binaryFloat, binaryChecksum
+//	numNodes  uint32
+//	source    uint32
+//	sink      uint32
+//	numArcs   uint64
+//	arcs      numArcs times: from uint32, to uint32, capacity int64 or float64 bits
+//	checksum  uint32   CRC-32C of all the above, if flags has binaryChecksum
+//
+// The arcs are in ArcID order.
+const (
+	binaryMagic      = "PSDO"
+	binaryHeaderSize = 4 + 2 + 2 + 4 + 4 + 4 + 8
+	binaryArcSize    = 4 + 4 + 8
+)
+
+// BinaryVersion is the version of the binary format written by WriteBinary.
+const BinaryVersion = 1
+
+// flags of the binary format
+const (
+	binaryFloat    = 1 << iota // float64 capacities, rather than int64
+	binaryChecksum             // a CRC-32C checksum follows the arcs
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// binaryFlags returns the flags of the capacity type T.
+func binaryFlags[T Capacity]() uint16 {
+	var c T
+	if _, ok := any(c).(float64); ok {
+		return binaryFloat
+	}
+	return 0
+}
+
+// WriteBinary writes the network of nw, its nodes, source, sink and arcs
+// with their current capacities, to w in the binary format read by
+// ReadBinary, followed by a checksum if checksum is set. Node numbers
+// must fit in 32 bits.
+func (nw *NetworkOf[T]) WriteBinary(w io.Writer, checksum bool) error {
+	if nw.numNodes > math.MaxUint32 {
+		return fmt.Errorf("%d nodes do not fit the binary format", nw.numNodes)
+	}
+	flags := binaryFlags[T]()
+	if checksum {
+		flags |= binaryChecksum
+	}
+
+	bw := bufio.NewWriterSize(w, 1<<16)
+	crc := crc32.New(castagnoli)
+	out := io.Writer(bw)
+	if checksum {
+		out = io.MultiWriter(bw, crc)
+	}
+
+	b := make([]byte, 0, 1<<16)
+	b = append(b, binaryMagic...)
+	b = binary.LittleEndian.AppendUint16(b, BinaryVersion)
+	b = binary.LittleEndian.AppendUint16(b, flags)
+	b = binary.LittleEndian.AppendUint32(b, uint32(nw.numNodes))
+	b = binary.LittleEndian.AppendUint32(b, uint32(nw.source))
+	b = binary.LittleEndian.AppendUint32(b, uint32(nw.sink))
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(nw.arcs)))
+	for _, a := range nw.arcs {
+		if len(b)+binaryArcSize > cap(b) {
+			if _, err := out.Write(b); err != nil {
+				return err
+			}
+			b = b[:0]
+		}
+		b = binary.LittleEndian.AppendUint32(b, uint32(a.from.number))
+		b = binary.LittleEndian.AppendUint32(b, uint32(a.to.number))
+		b = binary.LittleEndian.AppendUint64(b, capacityBits(a.capacity))
+	}
+	if _, err := out.Write(b); err != nil {
+		return err
+	}
+	if checksum {
+		if _, err := bw.Write(binary.LittleEndian.AppendUint32(nil, crc.Sum32())); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// capacityBits and bitsCapacity convert a capacity to and from its 64 bits
+// in the binary format.
+func capacityBits[T Capacity](c T) uint64 {
+	switch c := any(c).(type) {
+	case int64:
+		return uint64(c)
+	case float64:
+		return math.Float64bits(c)
+	}
+	return 0
+}
+
+func bitsCapacity[T Capacity](bits uint64) T {
+	var c T
+	switch p := any(&c).(type) {
+	case *int64:
+		*p = int64(bits)
+	case *float64:
+		*p = math.Float64frombits(bits)
+	}
+	return c
+}
+
+// ReadBinary reads a network written by WriteBinary into nw, as
+// ReadDimacs reads a DIMACS file; the capacity type of the input must be
+// T. The nodes, whose number is limited to MaxNodes, are allocated from the
+// header, and the arcs are read straight into the network, the node numbers
+// of each checked as it is read and the checksum, if any, at the end.
+func (nw *NetworkOf[T]) ReadBinary(r io.Reader) error {
+	nw.discard()
+	nw.timer.start = time.Now()
+	nw.timer.read = true
+	nw.startPhase(PhaseReadFile)
+	log := nw.logger()
+	if err := nw.readBinary(r); err != nil {
+		log.Debug("read failed", "err", err)
+		return err
+	}
+
+	err := nw.build()
+	nw.timer.readfile = time.Now()
+	if err == nil {
+		log.Debug("read network", "nodes", nw.numNodes, "arcs", nw.numArcs,
+			"source", nw.source, "sink", nw.sink)
+		nw.finishPhase(PhaseReadFile)
+	}
+	return err
+}
+
+func (nw *NetworkOf[T]) readBinary(r io.Reader) error {
+	var crc hash.Hash32
+	b := make([]byte, 4096*binaryArcSize)
+	read := func(b []byte) error {
+		if _, err := io.ReadFull(r, b); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		if crc != nil {
+			crc.Write(b)
+		}
+		return nil
+	}
+
+	h := b[:binaryHeaderSize]
+	if err := read(h); err != nil {
+		return err
+	}
+	if string(h[:4]) != binaryMagic {
+		return fmt.Errorf("not a binary network")
+	}
+	if v := binary.LittleEndian.Uint16(h[4:]); v != BinaryVersion {
+		return fmt.Errorf("binary network version %d, want %d", v, BinaryVersion)
+	}
+	flags := binary.LittleEndian.Uint16(h[6:])
+	if flags&^(binaryFloat|binaryChecksum) != 0 {
+		return fmt.Errorf("unknown binary network flags %#x", flags)
+	}
+	if flags&binaryFloat != binaryFlags[T]() {
+		var c T
+		return fmt.Errorf("binary network capacities are not %T", c)
+	}
+	if flags&binaryChecksum != 0 {
+		crc = crc32.New(castagnoli)
+		crc.Write(h)
+	}
+	numNodes := uint(binary.LittleEndian.Uint32(h[8:]))
+	source := uint(binary.LittleEndian.Uint32(h[12:]))
+	sink := uint(binary.LittleEndian.Uint32(h[16:]))
+	numArcs := binary.LittleEndian.Uint64(h[20:])
+	if numNodes > nw.maxNodes() {
+		return fmt.Errorf("%d nodes, more than the limit of %d", numNodes, nw.maxNodes())
+	}
+
+	// The arcs go straight into their slab, as many as the header has up to
+	// maxArcHint allocated at once; each is checked as it is read.
+	nw.init(numNodes, uint(min(numArcs, maxArcHint)))
+	nw.source, nw.sink = source, sink
+	nw.arcSlab = make([]arc[T], min(numArcs, maxArcHint))
+	for id := uint64(0); id < numArcs; {
+		n := min(numArcs-id, uint64(len(b)/binaryArcSize))
+		chunk := b[:n*binaryArcSize]
+		if err := read(chunk); err != nil {
+			return err
+		}
+		for c := chunk; len(c) > 0; c = c[binaryArcSize:] {
+			from := uint(binary.LittleEndian.Uint32(c))
+			to := uint(binary.LittleEndian.Uint32(c[4:]))
+			if from < 1 || from > numNodes || to < 1 || to > numNodes {
+				return fmt.Errorf("arc %d (%d, %d): node out of range 1..%d", id, from, to, numNodes)
+			}
+			if len(nw.arcSlab) == 0 {
+				nw.arcSlab = make([]arc[T], arcSlabSize)
+			}
+			a := &nw.arcSlab[0]
+			nw.arcSlab = nw.arcSlab[1:]
+			*a = arc[T]{
+				from:      nw.adjacencyList[from-1],
+				to:        nw.adjacencyList[to-1],
+				capacity:  bitsCapacity[T](binary.LittleEndian.Uint64(c[8:])),
+				direction: 1,
+				id:        ArcID(id),
+			}
+			nw.arcs = append(nw.arcs, a)
+			a.from.numAdjacent++
+			a.to.numAdjacent++
+			id++
+		}
+	}
+
+	if crc != nil {
+		sum := crc.Sum32()
+		crc = nil
+		if err := read(b[:4]); err != nil {
+			return err
+		}
+		if binary.LittleEndian.Uint32(b) != sum {
+			return fmt.Errorf("binary network checksum mismatch")
+		}
+	}
+	return nil
+}
+
+// ConvertDimacs reads a DIMACS max-flow problem from r, as ReadDimacs does,
+// and writes it to w in the binary format of WriteBinary.
+func ConvertDimacs(w io.Writer, r io.Reader, checksum bool) error {
+	nw := &Network{Logger: PseudoLogger}
+	if err := nw.ReadDimacs(r); err != nil {
+		return err
+	}
+	return nw.WriteBinary(w, checksum)
+}
@@ -0,0 +1,213 @@
+// Command pseudo solves a DIMACS maximum flow problem with Hochbaum's
+// pseudoflow algorithm, as the main program of the C source code does.
+//
+// Usage:
+//
+//	pseudo [flags] [file]
+//
+// The problem is read from file, or from standard input if file is absent
+// or "-". It may be compressed with gzip or bzip2, or be in the binary
+// format of pseudo.WriteBinary. The result is written to standard output
+// in the format selected by -format: the DIMACS text of pseudo.Result,
+// JSON or CSV.
+//
+// The flags -lowest and -fifo select the variant of the algorithm, as the
+// LOWEST_LABEL and FIFO_BUCKET switches of the C source code; they
+// override the settings read from a config.json file by -config.
+//
+// The node and arc counts and the timings of each step, which the C main
+// program always prints, are printed with -time, and the operation counts
+// of its STATS switch with -stats. They come before the result, as in C,
+// or go to standard error for -format csv.
+//
+// The exit status is 0 if the flow checks as feasible and optimal, 3 if it
+// does not, 2 for bad usage and 1 for any other error.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/qarth/pseudo"
+)
+
+// exit status
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitCheck
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("pseudo", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: pseudo [flags] [file]")
+		fs.PrintDefaults()
+	}
+	var ctx pseudo.Context
+	fs.BoolVar(&ctx.LowestLabel, "lowest", false, "use the lowest label pseudoflow algorithm, rather than the highest label")
+	fs.BoolVar(&ctx.FifoBucket, "fifo", false, "use FIFO buckets, rather than LIFO buckets")
+	fs.BoolVar(&ctx.DisplayCut, "cut", false, "display the source set of the min cut")
+	config := fs.String("config", "", "read the algorithm settings from the \"config\":\"pseudo\" object of JSON `file`")
+	displayFlow := fs.Bool("flow", true, "display the flow on each arc")
+	nonZero := fs.Bool("nonzero", false, "display only the arcs with flow")
+	flowLine := fs.String("flowline", "f", "flow line `type` of the dimacs format: f or a, as in the C source code")
+	format := fs.String("format", "dimacs", "output `format`: dimacs, json or csv")
+	stats := fs.Bool("stats", false, "display the operation counts of the solve")
+	timing := fs.Bool("time", false, "display the time of each step of the solve")
+	progress := fs.Bool("progress", false, "report the progress of the solve on standard error")
+	verbose := fs.Bool("v", false, "log debug events on standard error")
+	output := fs.String("o", "", "write the result to `file` rather than standard output")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	opts := pseudo.ResultOptions{OmitZeroFlow: *nonZero, OmitArcs: !*displayFlow}
+	var err error
+	if opts.Format, err = pseudo.ParseResultFormat(*format); err != nil {
+		fmt.Fprintln(stderr, "pseudo:", err)
+		return exitUsage
+	}
+	if *flowLine != "f" && *flowLine != "a" {
+		fmt.Fprintf(stderr, "pseudo: flow line type %q is not f or a\n", *flowLine)
+		return exitUsage
+	}
+	opts.FlowLine = (*flowLine)[0]
+
+	nw := new(pseudo.Network)
+	if *config != "" {
+		if err := nw.Config(*config); err != nil {
+			fmt.Fprintln(stderr, "pseudo:", err)
+			return exitError
+		}
+		// flags given on the command line override the config file
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "lowest":
+				nw.Ctx.LowestLabel = ctx.LowestLabel
+			case "fifo":
+				nw.Ctx.FifoBucket = ctx.FifoBucket
+			case "cut":
+				nw.Ctx.DisplayCut = ctx.DisplayCut
+			}
+		})
+	} else {
+		nw.Ctx = ctx
+	}
+	if *progress {
+		nw.Observer = pseudo.ProgressPrinter(stderr)
+	}
+	if *verbose {
+		nw.Logger = slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+
+	input := "stdin"
+	in := stdin
+	if name := fs.Arg(0); name != "" && name != "-" && strings.ToLower(name) != "stdin" {
+		fh, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(stderr, "pseudo:", err)
+			return exitError
+		}
+		defer fh.Close()
+		input, in = name, fh
+	}
+	if err := read(nw, in); err != nil {
+		fmt.Fprintf(stderr, "pseudo: %s: %v\n", input, err)
+		return exitError
+	}
+	sol, err := nw.Solve()
+	if err != nil {
+		fmt.Fprintf(stderr, "pseudo: %s: %v\n", input, err)
+		return exitError
+	}
+
+	out, closeOut := stdout, func() error { return nil }
+	if *output != "" {
+		fh, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(stderr, "pseudo:", err)
+			return exitError
+		}
+		out, closeOut = fh, fh.Close
+	}
+	opts.Header = "Data: " + input
+	// The summary comes before the result, as in C source main(). The JSON
+	// document holds the statistics and timings; CSV has no place for them.
+	switch opts.Format {
+	case pseudo.FormatDimacs:
+		err = summary(out, nw, sol, *stats, *timing)
+	case pseudo.FormatCSV:
+		err = summary(stderr, nw, sol, *stats, *timing)
+	}
+	if err == nil {
+		err = sol.WriteResult(out, opts)
+	}
+	if cerr := closeOut(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "pseudo:", err)
+		return exitError
+	}
+
+	if !sol.Feasible || !sol.Optimal {
+		fmt.Fprintf(stderr, "pseudo: %s: the flow is not feasible and optimal\n", input)
+		for _, v := range sol.Violations {
+			fmt.Fprintln(stderr, "pseudo:", v)
+		}
+		return exitCheck
+	}
+	return exitOK
+}
+
+// read reads nw from r, which holds a DIMACS problem or, detected by its
+// magic bytes, a binary network.
+func read(nw *pseudo.Network, r io.Reader) error {
+	buf := bufio.NewReader(r)
+	if magic, _ := buf.Peek(4); string(magic) == "PSDO" {
+		return nw.ReadBinary(buf)
+	}
+	return nw.ReadDimacs(buf)
+}
+
+// summary writes the comment lines of C source main() for the timings
+// and, with the STATS switch, the statistics of a solve.
+func summary(w io.Writer, nw *pseudo.Network, sol *pseudo.Solution, stats, timing bool) error {
+	if timing {
+		t := sol.Timings
+		fmt.Fprintf(w, "c Number of nodes     : %d\n", nw.NumNodes())
+		fmt.Fprintf(w, "c Number of arcs      : %d\n", nw.NumArcs())
+		fmt.Fprintf(w, "c Time to read        : %.3f\n", t.ReadDimacsFile.Seconds())
+		fmt.Fprintf(w, "c Time to initialize  : %.3f\n", t.SimpleInitialization.Seconds())
+		fmt.Fprintf(w, "c Time to min cut     : %.3f\n", (t.SimpleInitialization + t.FlowPhaseOne).Seconds())
+		fmt.Fprintf(w, "c Time to max flow    : %.3f\n", (t.SimpleInitialization + t.FlowPhaseOne + t.RecoverFlow).Seconds())
+	}
+	if stats {
+		s := sol.Stats
+		fmt.Fprintf(w, "c Number of arc scans : %d\n", s.NumArcScans)
+		fmt.Fprintf(w, "c Number of mergers   : %d\n", s.NumMergers)
+		fmt.Fprintf(w, "c Number of pushes    : %d\n", s.NumPushes)
+		fmt.Fprintf(w, "c Number of relabels  : %d\n", s.NumRelabels)
+		_, err := fmt.Fprintf(w, "c Number of gaps      : %d\n", s.NumGaps)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const maxfFile = "../../examples/dimacsMaxf.txt"
+
+func TestRun(t *testing.T) {
+	data, err := os.ReadFile(maxfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(config, []byte(`{"config":"pseudo","lowestlabel":true,"fifobucket":true}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		args   []string
+		stdin  string
+		status int
+		want   []string // in the output
+		not    []string // not in the output
+	}{
+		{[]string{maxfFile}, "", exitOK,
+			[]string{"c Data: " + maxfFile, "c Highest label", "c Using LIFO buckets", "s 15", "f 1 2 5\nf 1 3 10\n"},
+			[]string{"n 1", "c Number of"}},
+		{[]string{"-lowest", "-fifo", "-cut", "-flowline", "a", "-stats", "-time"}, string(data), exitOK,
+			[]string{"c Data: stdin", "c Lowest label", "c Using FIFO buckets", "n 1\n", "a 1 3 10\n", "c Number of arcs      : 8\n", "c Number of pushes"},
+			[]string{"f 1 3"}},
+		{[]string{"-config", config, "-fifo=false", "-flow=false", "-"}, string(data), exitOK,
+			[]string{"c Lowest label", "c Using LIFO buckets", "s 15"},
+			[]string{"SRC DST FLOW", "f 1 2"}},
+		{[]string{"-format", "csv", "-nonzero", maxfFile}, "", exitOK,
+			[]string{"id,from,to,flow,capacity\n0,1,2,5,5\n1,1,3,10,15\n"},
+			[]string{"c ", ",0,"}},
+		{[]string{"-format", "xml", maxfFile}, "", exitUsage, nil, nil},
+		{[]string{"-flowline", "x", maxfFile}, "", exitUsage, nil, nil},
+		{[]string{"-nosuchflag"}, "", exitUsage, nil, nil},
+		{[]string{maxfFile, maxfFile}, "", exitUsage, nil, nil},
+		{[]string{"nosuchfile"}, "", exitError, nil, nil},
+		{[]string{}, "p max 2 1\nn 1 s\n", exitError, nil, nil},
+		{[]string{"-config", "nosuchfile", maxfFile}, "", exitError, nil, nil},
+	} {
+		var stdout, stderr bytes.Buffer
+		status := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+		if status != tc.status {
+			t.Errorf("%q: exit status %d, want %d: %s", tc.args, status, tc.status, stderr.String())
+		}
+		for _, w := range tc.want {
+			if !strings.Contains(stdout.String(), w) {
+				t.Errorf("%q: output has no %q:\n%s", tc.args, w, stdout.String())
+			}
+		}
+		for _, n := range tc.not {
+			if strings.Contains(stdout.String(), n) {
+				t.Errorf("%q: output has %q:\n%s", tc.args, n, stdout.String())
+			}
+		}
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-stats", "-time", maxfFile}, nil, &stdout, &stderr); status != exitOK {
+		t.Fatalf("exit status %d: %s", status, stderr.String())
+	}
+	out := stdout.String()
+	if !strings.HasPrefix(out, "c Number of nodes") || strings.Index(out, "c Number of gaps") > strings.Index(out, "c Data: ") {
+		t.Errorf("summary not before the result:\n%s", out)
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "result.json")
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-format", "json", "-o", out, maxfFile}, nil, &stdout, &stderr); status != exitOK {
+		t.Fatalf("exit status %d: %s", status, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("output %q on standard output", stdout.String())
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Flow     int64
+		Feasible bool
+		Optimal  bool
+		Arcs     []struct{ Flow int64 }
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("%v: %s", err, data)
+	}
+	if doc.Flow != 15 || !doc.Feasible || !doc.Optimal || len(doc.Arcs) != 8 {
+		t.Errorf("result %+v", doc)
+	}
+}
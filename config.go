@@ -1,4 +1,4 @@
-// config.go - set PseudoCtx from a config file with a "config":"pseudo" JSON object.
+// config.go - set PseudoCtx, or a Network's Ctx, from a config file with a "config":"pseudo" JSON object.
 
 package pseudo
 
@@ -21,6 +21,15 @@ import (
 //	  "fifobucket": true
 //	}
 func Config(file string) error {
+	return configContext(file, &PseudoCtx)
+}
+
+// Config sets nw.Ctx from file as for the package-level Config.
+func (nw *NetworkOf[T]) Config(file string) error {
+	return configContext(file, &nw.Ctx)
+}
+
+func configContext(file string, ctx *Context) error {
 	// read file into an array of JSON objects
 	objs, err := checkjson.ReadJSONFile(file)
 	if err != nil {
@@ -44,10 +53,10 @@ func Config(file string) error {
 			if ctxset {
 				return fmt.Errorf("duplicate 'pseudo' entry in config file: %s entry: %d", file, n)
 			}
-			if err := checkjson.Validate(obj, PseudoCtx); err != nil {
+			if err := checkjson.Validate(obj, *ctx); err != nil {
 				return fmt.Errorf("checking pseudo config JSON object: %s", err)
 			}
-			if err := json.Unmarshal(obj, &PseudoCtx); err != nil {
+			if err := json.Unmarshal(obj, ctx); err != nil {
 				return fmt.Errorf("config file: %s - %s", file, err)
 			}
 			ctxset = true
@@ -0,0 +1,125 @@
+// cut.go - the minimum s-t cut found by FlowPhaseOne.
+
+package pseudo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// CutOf is a minimum s-t cut of a NetworkOf[T].
+type CutOf[T Capacity] struct {
+	// SourceSet lists the nodes on the source side of the cut in increasing order.
+	SourceSet []uint
+	// Arcs are the arcs from the source side to the sink side, in ArcID order.
+	// A maximum flow saturates each of them.
+	Arcs []CutArcOf[T]
+	// Capacity is the sum of the capacities of Arcs, the value of a maximum flow.
+	Capacity T
+}
+
+// Cut is a minimum s-t cut of a Network.
+type Cut = CutOf[int64]
+
+// CutArcOf is an arc crossing a CutOf[T].
+type CutArcOf[T Capacity] struct {
+	ID       ArcID
+	From, To uint
+	Capacity T
+}
+
+// CutArc is an arc crossing a Cut.
+type CutArc = CutArcOf[int64]
+
+// MinCut returns the minimum cut of nw. It is determined once FlowPhaseOne
+// has run and is not changed by RecoverFlow.
+func (nw *NetworkOf[T]) MinCut() *CutOf[T] {
+	gap := nw.gap()
+	cut := new(CutOf[T])
+
+	for _, n := range nw.adjacencyList {
+		if n.label >= gap {
+			cut.SourceSet = append(cut.SourceSet, n.number)
+		}
+	}
+	for id, a := range nw.arcs {
+		if a.from.label >= gap && a.to.label < gap {
+			cut.Arcs = append(cut.Arcs, CutArcOf[T]{
+				ID:       ArcID(id),
+				From:     a.from.number,
+				To:       a.to.number,
+				Capacity: a.capacity,
+			})
+			cut.Capacity += a.capacity
+		}
+	}
+
+	return cut
+}
+
+// SolveMinCut runs SimpleInitialization and FlowPhaseOne on a Network that has
+// been read or constructed but not yet initialized, and returns its MinCut.
+// It skips RecoverFlow, so the arc flows of nw are not a valid flow afterwards.
+func (nw *NetworkOf[T]) SolveMinCut() (*CutOf[T], error) {
+	nw.startSolve()
+	if err := nw.startPhaseOne(); err != nil {
+		return nil, err
+	}
+	nw.timer.initialize = time.Now()
+	if err := nw.runPhaseOne(context.Background()); err != nil {
+		return nil, err
+	}
+	nw.timer.flow = time.Now()
+	nw.timer.recflow = nw.timer.flow
+
+	return nw.MinCut(), nil
+}
+
+// SolveMinCut is Solve that stops after FlowPhaseOne and returns just the
+// minimum cut. If input == "stdin" then os.Stdin is read.
+func SolveMinCut(input string) (*Cut, error) {
+	var r io.Reader = os.Stdin
+	if strings.ToLower(input) != "stdin" {
+		fh, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		defer fh.Close()
+		r = fh
+	}
+
+	nw := &Network{Ctx: PseudoCtx, Logger: PseudoLogger}
+	if err := nw.ReadDimacs(r); err != nil {
+		return nil, err
+	}
+	cut, err := nw.SolveMinCut()
+	if err != nil {
+		return nil, err
+	}
+	setStd(nw)
+
+	return cut, nil
+}
+
+// Dimacs returns the source set of the Cut as "n" lines, as
+// displayCut of C source code.
+//
+// Example:
+//
+//	c
+//	c Nodes in source set of min s-t cut:
+//	n 1
+//	n 3
+//	...
+func (c *CutOf[T]) Dimacs() []string {
+	ret := []string{"c ", "c Nodes in source set of min s-t cut:"}
+	for _, n := range c.SourceSet {
+		ret = append(ret, fmt.Sprintf("n %d", n))
+	}
+
+	return ret
+}
@@ -0,0 +1,441 @@
+// dimacs.go - read, validate and write the DIMACS max-flow format of ReadDimacsFile.
+
+package pseudo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+)
+
+// ParseError is the error returned by ReadDimacs, ReadMinCost and
+// ReadAssignment for input that is not a valid DIMACS problem. Line and
+// Column, the byte offset in the line, count from 1. Errors of the input
+// as a whole, such as a missing sink, have Column 0 and the number of
+// lines read as Line.
+type ParseError struct {
+	Line, Column uint
+	Msg          string
+}
+
+func (e *ParseError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// maxArcHint bounds the arcs allocated ahead of the "a" lines from the
+// arc count of the "p" line, which has not been checked yet.
+const maxArcHint = 1 << 24
+
+// DefaultMaxNodes is the MaxNodes of a Network that has none, and the
+// limit of ReadMinCost and ReadAssignment when they are given none.
+const DefaultMaxNodes = 1 << 26
+
+// dimacsLine splits a line into the fields of a DIMACS line.
+type dimacsLine struct {
+	b    []byte
+	pos  uint
+	line uint
+}
+
+// field returns the next field of l and its column, or nil and the column
+// after the end of the line if there is none.
+func (l *dimacsLine) field() ([]byte, uint) {
+	for l.pos < uint(len(l.b)) && (l.b[l.pos] == ' ' || l.b[l.pos] == '\t') {
+		l.pos++
+	}
+	start := l.pos
+	for l.pos < uint(len(l.b)) && l.b[l.pos] != ' ' && l.b[l.pos] != '\t' {
+		l.pos++
+	}
+	if start == l.pos {
+		return nil, start + 1
+	}
+	return l.b[start:l.pos], start + 1
+}
+
+func (l *dimacsLine) errorf(column uint, format string, args ...any) *ParseError {
+	return &ParseError{Line: l.line, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// number returns the next field of l as an unsigned number and its column;
+// what names it in errors.
+func (l *dimacsLine) number(what string) (uint, uint, error) {
+	f, col := l.field()
+	if f == nil {
+		return 0, col, l.errorf(col, "missing %s", what)
+	}
+	n, err := parseUint(f)
+	if err != nil {
+		return 0, col, l.errorf(col, "%s %q: %v", what, f, err)
+	}
+	return uint(n), col, nil
+}
+
+// integer returns the next field of l as a signed number; what names it in errors.
+func (l *dimacsLine) integer(what string) (int64, error) {
+	f, col := l.field()
+	if f == nil {
+		return 0, l.errorf(col, "missing %s", what)
+	}
+	n, err := parseCapacity[int64](f)
+	if err != nil {
+		return 0, l.errorf(col, "%s %q: %v", what, f, err)
+	}
+	return n, nil
+}
+
+// problem returns the number of nodes, its column, and the number of arcs
+// of the rest of a "p" line of problem type typ. The nodes, which are
+// allocated at once, are limited to maxNodes.
+func (l *dimacsLine) problem(typ string, maxNodes uint) (uint, uint, uint, error) {
+	f, col := l.field()
+	if string(f) != typ {
+		return 0, 0, 0, l.errorf(col, "problem type %q is not %s", f, typ)
+	}
+	n, nodeCol, err := l.number("number of nodes")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if n > maxNodes {
+		return 0, 0, 0, l.errorf(nodeCol, "%d nodes, more than the limit of %d", n, maxNodes)
+	}
+	m, _, err := l.number("number of arcs")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if err := l.end(); err != nil {
+		return 0, 0, 0, err
+	}
+	return n, nodeCol, m, nil
+}
+
+// node returns the next field of l as a node of a network of numNodes nodes.
+func (l *dimacsLine) node(what string, numNodes uint) (uint, error) {
+	n, col, err := l.number(what)
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 || n > numNodes {
+		return 0, l.errorf(col, "%s %d out of range 1..%d", what, n, numNodes)
+	}
+	return n, nil
+}
+
+// end checks that there are no more fields in l.
+func (l *dimacsLine) end() error {
+	if f, col := l.field(); f != nil {
+		return l.errorf(col, "unexpected %q", f)
+	}
+	return nil
+}
+
+// decompress returns a buffered reader of r that transparently decompresses
+// gzip or bzip2 input, detected by its magic bytes; other input is read as is.
+func decompress(r io.Reader) (*bufio.Reader, error) {
+	buf := bufio.NewReaderSize(r, 1<<16)
+	magic, _ := buf.Peek(3) // short input is not compressed
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(buf)
+		if err != nil {
+			return nil, err
+		}
+		return bufio.NewReaderSize(zr, 1<<16), nil
+	case bytes.Equal(magic, []byte("BZh")):
+		return bufio.NewReaderSize(bzip2.NewReader(buf), 1<<16), nil
+	}
+	return buf, nil
+}
+
+// parseUint parses the decimal digits of b, as strconv.ParseUint does
+// without allocating, and fails with strconv.ErrSyntax or strconv.ErrRange.
+func parseUint(b []byte) (uint64, error) {
+	if len(b) == 0 {
+		return 0, strconv.ErrSyntax
+	}
+	var n uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, strconv.ErrSyntax
+		}
+		d := uint64(c - '0')
+		if n > (math.MaxUint64-d)/10 {
+			return 0, strconv.ErrRange
+		}
+		n = n*10 + d
+	}
+	return n, nil
+}
+
+// parseCapacity parses b as a capacity of type T, failing with
+// strconv.ErrSyntax or strconv.ErrRange.
+func parseCapacity[T Capacity](b []byte) (T, error) {
+	var c T
+	switch p := any(&c).(type) {
+	case *int64:
+		neg := len(b) > 0 && b[0] == '-'
+		if len(b) > 0 && (b[0] == '-' || b[0] == '+') {
+			b = b[1:]
+		}
+		n, err := parseUint(b)
+		if err != nil {
+			return c, err
+		}
+		switch {
+		case neg && n <= -math.MinInt64:
+			*p = int64(-n)
+		case !neg && n <= math.MaxInt64:
+			*p = int64(n)
+		default:
+			return c, strconv.ErrRange
+		}
+	case *float64:
+		// The conversion does not allocate: ParseFloat keeps no reference
+		// to its argument, copying it into the errors it returns.
+		f, err := strconv.ParseFloat(string(b), 64)
+		if err != nil {
+			return c, err.(*strconv.NumError).Err
+		}
+		*p = f
+	}
+	return c, nil
+}
+
+// dimacsReader reads the lines of DIMACS input, decompressed, skipping
+// comment and blank lines.
+type dimacsReader struct {
+	buf  *bufio.Reader
+	long []byte // lines longer than the buffer of buf
+	l    dimacsLine
+}
+
+func newDimacsReader(r io.Reader) (*dimacsReader, error) {
+	buf, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	return &dimacsReader{buf: buf}, nil
+}
+
+// next reads the next line that is not a comment or blank into d.l and
+// returns its line type, the one byte first field, and its column. It
+// returns io.EOF at the end of the input.
+func (d *dimacsReader) next() ([]byte, uint, error) {
+	for {
+		b, err := d.buf.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			d.long = append(d.long[:0], b...)
+			for err == bufio.ErrBufferFull {
+				b, err = d.buf.ReadSlice('\n')
+				d.long = append(d.long, b...)
+			}
+			b = d.long
+		}
+		if err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		if len(b) == 0 {
+			return nil, 0, io.EOF // nothing more to process
+		}
+		// Strip off EOL; the last line may have none.
+		if b[len(b)-1] == '\n' {
+			b = b[:len(b)-1]
+		}
+		if len(b) > 0 && b[len(b)-1] == '\r' {
+			b = b[:len(b)-1]
+		}
+		d.l = dimacsLine{b: b, line: d.l.line + 1}
+		if len(b) > 0 && b[0] == 'c' {
+			continue // "comment" lines
+		}
+
+		f, col := d.l.field()
+		if f == nil {
+			continue // blank lines are not in the spec, but harmless
+		}
+		if len(f) != 1 {
+			return nil, col, d.l.errorf(col, "unknown line type %q", f)
+		}
+		return f, col, nil
+	}
+}
+
+// endError returns the *ParseError of the input as a whole read by d.
+func (d *dimacsReader) endError(format string, args ...any) *ParseError {
+	return &ParseError{Line: d.l.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseDimacs reads the "p", "n" and "a" lines of r into nw, checking them
+// as it goes, and returns the number of lines read. Lines starting with 'c'
+// and blank lines are skipped. Compressed input is decompressed.
+func (nw *NetworkOf[T]) parseDimacs(r io.Reader, log *slog.Logger) (uint, error) {
+	var numNodes, numArcs, arcs, sourceLine, sinkLine uint
+	var total T
+	maxTotal := maxCapacity[T]()
+	problem := false
+	d, err := newDimacsReader(r)
+	if err != nil {
+		log.Debug("read error", "line", 0, "err", err)
+		return 0, err
+	}
+	l := &d.l
+	for {
+		f, col, err := d.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*ParseError); !ok {
+				log.Debug("read error", "line", l.line+1, "err", err)
+			}
+			return l.line, err
+		}
+		if f[0] != 'p' && !problem {
+			return l.line, l.errorf(col, "%q line before problem line", f)
+		}
+		switch f[0] {
+		case 'p':
+			if problem {
+				return l.line, l.errorf(col, "second problem line")
+			}
+			problem = true
+			n, col, m, err := l.problem("max", nw.maxNodes())
+			if err != nil {
+				return l.line, err
+			}
+			if n < 2 {
+				return l.line, l.errorf(col, "%d nodes, a source and a sink need 2", n)
+			}
+			numNodes, numArcs = n, m
+			log.Debug("problem line", "line", l.line, "type", "max", "nodes", numNodes, "arcs", numArcs)
+			nw.init(numNodes, min(numArcs, maxArcHint))
+		case 'n':
+			i, err := l.node("node", numNodes)
+			if err != nil {
+				return l.line, err
+			}
+			f, col = l.field()
+			switch string(f) {
+			case "s":
+				if sourceLine != 0 {
+					return l.line, l.errorf(col, "second source, the first is on line %d", sourceLine)
+				}
+				log.Debug("source node", "line", l.line, "node", i)
+				nw.source, sourceLine = i, l.line
+			case "t":
+				if sinkLine != 0 {
+					return l.line, l.errorf(col, "second sink, the first is on line %d", sinkLine)
+				}
+				log.Debug("sink node", "line", l.line, "node", i)
+				nw.sink, sinkLine = i, l.line
+			default:
+				return l.line, l.errorf(col, "node designation %q is not s or t", f)
+			}
+			if err := l.end(); err != nil {
+				return l.line, err
+			}
+		case 'a':
+			if arcs == numArcs {
+				return l.line, l.errorf(col, "more arc lines than the %d of the problem line", numArcs)
+			}
+			from, err := l.node("from node", numNodes)
+			if err != nil {
+				return l.line, err
+			}
+			to, err := l.node("to node", numNodes)
+			if err != nil {
+				return l.line, err
+			}
+			f, col = l.field()
+			if f == nil {
+				return l.line, l.errorf(col, "missing capacity")
+			}
+			capacity, err := parseCapacity[T](f)
+			if err != nil {
+				return l.line, l.errorf(col, "capacity %q: %v", f, err)
+			}
+			if capacity != capacity || capacity < 0 {
+				return l.line, l.errorf(col, "capacity %v is not non-negative", capacity)
+			}
+			if total > maxTotal-capacity {
+				return l.line, l.errorf(col, "total arc capacity overflows %T", total)
+			}
+			total += capacity
+			if err := l.end(); err != nil {
+				return l.line, err
+			}
+			nw.addArc(from, to, capacity)
+			arcs++
+		default:
+			return l.line, l.errorf(col, "unknown line type %q", f)
+		}
+	}
+
+	end := func(format string, args ...any) (uint, error) {
+		return l.line, d.endError(format, args...)
+	}
+	switch {
+	case !problem:
+		return end("no problem line")
+	case arcs != numArcs:
+		return end("%d arc lines, the problem line has %d", arcs, numArcs)
+	case sourceLine == 0:
+		return end("no source node")
+	case sinkLine == 0:
+		return end("no sink node")
+	case nw.source == nw.sink:
+		return end("source and sink are both node %d, on lines %d and %d", nw.source, sourceLine, sinkLine)
+	}
+	return l.line, nil
+}
+
+// WriteDimacs writes the network of nw to w as a DIMACS max-flow problem
+// that ReadDimacs reads back: the "p max" line, the "n" lines of the source
+// and the sink and an "a" line for each arc with its current capacity, in
+// ArcID order, the order of the input or of AddArc.
+func (nw *NetworkOf[T]) WriteDimacs(w io.Writer) error {
+	if nw.source < 1 || nw.source > nw.numNodes {
+		return fmt.Errorf("no source node")
+	}
+	if nw.sink < 1 || nw.sink > nw.numNodes {
+		return fmt.Errorf("no sink node")
+	}
+
+	bw := bufio.NewWriterSize(w, 1<<16)
+	fmt.Fprintf(bw, "p max %d %d\nn %d s\nn %d t\n", nw.numNodes, len(nw.arcs), nw.source, nw.sink)
+	b := make([]byte, 0, 64)
+	for _, a := range nw.arcs {
+		b = append(b[:0], "a "...)
+		b = strconv.AppendUint(b, uint64(a.from.number), 10)
+		b = append(b, ' ')
+		b = strconv.AppendUint(b, uint64(a.to.number), 10)
+		b = append(b, ' ')
+		b = appendCapacity(b, a.capacity)
+		b = append(b, '\n')
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// appendCapacity appends c to b in the shortest form parseCapacity reads
+// back exactly.
+func appendCapacity[T Capacity](b []byte, c T) []byte {
+	switch c := any(c).(type) {
+	case int64:
+		return strconv.AppendInt(b, c, 10)
+	case float64:
+		return strconv.AppendFloat(b, c, 'g', -1, 64)
+	}
+	return b
+}
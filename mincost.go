@@ -0,0 +1,440 @@
+// mincost.go - minimum cost flow for DIMACS "p min" problems.
+// See: http://lpsolve.sourceforge.net/5.5/DIMACS_mcf.htm
+
+package pseudo
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// MinCostNetwork is a minimum cost flow problem: node supplies (positive)
+// and demands (negative), and arcs with a lower bound, an upper bound and a
+// cost per unit of flow. It is solved by successive shortest paths rather
+// than pseudoflow, which only finds maximum flows.
+type MinCostNetwork struct {
+	numNodes uint
+	supply   []int64
+	arcs     []MinCostArc
+}
+
+// MinCostArc is an arc of a MinCostNetwork and, in a MinCostSolution,
+// the flow on it.
+type MinCostArc struct {
+	ID        ArcID
+	From, To  uint
+	Low, High int64
+	Cost      int64
+	Flow      int64
+}
+
+// MinCostSolution is an optimal flow of a MinCostNetwork.
+type MinCostSolution struct {
+	// Cost is the total cost of the flow.
+	Cost int64
+	// Arcs holds the flow on each arc in ArcID order.
+	Arcs []MinCostArc
+}
+
+// NewMinCostNetwork returns a MinCostNetwork with nodes numbered 1 to numNodes,
+// no supplies and no arcs.
+func NewMinCostNetwork(numNodes uint) *MinCostNetwork {
+	return &MinCostNetwork{
+		numNodes: numNodes,
+		supply:   make([]int64, numNodes),
+	}
+}
+
+// SetSupply sets the supply of node n; a demand is a negative supply.
+// It panics if n is not in the network.
+func (m *MinCostNetwork) SetSupply(n uint, supply int64) {
+	m.checkNode("SetSupply", n)
+	m.supply[n-1] = supply
+}
+
+// AddArc adds an arc from node 'from' to node 'to' whose flow must lie
+// between low and high, at cost per unit of flow, and returns its ArcID.
+// It panics if either node is not in the network.
+func (m *MinCostNetwork) AddArc(from, to uint, low, high, cost int64) ArcID {
+	m.checkNode("AddArc", from)
+	m.checkNode("AddArc", to)
+	id := ArcID(len(m.arcs))
+	m.arcs = append(m.arcs, MinCostArc{ID: id, From: from, To: to, Low: low, High: high, Cost: cost})
+	return id
+}
+
+func (m *MinCostNetwork) checkNode(fn string, n uint) {
+	if n < 1 || n > m.numNodes {
+		panic(fmt.Sprintf("pseudo: %s(%d): node out of range 1..%d", fn, n, m.numNodes))
+	}
+}
+
+// ReadMinCost reads a DIMACS "p min" problem from r, which may be
+// compressed with gzip or bzip2. Input that is not a valid "p min" problem
+// returns a *ParseError, as for ReadDimacs. maxNodes is the largest number
+// of nodes accepted from the problem line, as MaxNodes is for ReadDimacs;
+// 0 means DefaultMaxNodes.
+//
+// Example:
+//
+//	p min 4 5
+//	n 1 4
+//	n 4 -4
+//	a 1 2 0 4 2
+//	...
+func ReadMinCost(r io.Reader, maxNodes uint) (*MinCostNetwork, error) {
+	if maxNodes == 0 {
+		maxNodes = DefaultMaxNodes
+	}
+	var m *MinCostNetwork
+	var numArcs uint
+	d, err := newDimacsReader(r)
+	if err != nil {
+		return nil, err
+	}
+	l := &d.l
+	for {
+		f, col, err := d.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if f[0] != 'p' && m == nil {
+			return nil, l.errorf(col, "%q line before problem line", f)
+		}
+
+		switch f[0] {
+		case 'p':
+			if m != nil {
+				return nil, l.errorf(col, "second problem line")
+			}
+			n, _, arcs, err := l.problem("min", maxNodes)
+			if err != nil {
+				return nil, err
+			}
+			m, numArcs = NewMinCostNetwork(n), arcs
+		case 'n':
+			i, err := l.node("node", m.numNodes)
+			if err != nil {
+				return nil, err
+			}
+			supply, err := l.integer("supply")
+			if err != nil {
+				return nil, err
+			}
+			if err := l.end(); err != nil {
+				return nil, err
+			}
+			m.supply[i-1] = supply
+		case 'a':
+			if uint(len(m.arcs)) == numArcs {
+				return nil, l.errorf(col, "more arc lines than the %d of the problem line", numArcs)
+			}
+			from, err := l.node("from node", m.numNodes)
+			if err != nil {
+				return nil, err
+			}
+			to, err := l.node("to node", m.numNodes)
+			if err != nil {
+				return nil, err
+			}
+			low, err := l.integer("lower bound")
+			if err != nil {
+				return nil, err
+			}
+			high, err := l.integer("upper bound")
+			if err != nil {
+				return nil, err
+			}
+			cost, err := l.integer("cost")
+			if err != nil {
+				return nil, err
+			}
+			if err := l.end(); err != nil {
+				return nil, err
+			}
+			m.AddArc(from, to, low, high, cost)
+		default:
+			return nil, l.errorf(col, "unknown line type %q", f)
+		}
+	}
+	if m == nil {
+		return nil, d.endError("no problem line")
+	}
+	if uint(len(m.arcs)) != numArcs {
+		return nil, d.endError("%d arc lines, the problem line has %d", len(m.arcs), numArcs)
+	}
+
+	return m, nil
+}
+
+// SolveMinCost reads a "p min" problem from the input file and solves it.
+// If input == "stdin" then os.Stdin is read.
+func SolveMinCost(input string) (*MinCostSolution, error) {
+	var r io.Reader = os.Stdin
+	if strings.ToLower(input) != "stdin" {
+		fh, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		defer fh.Close()
+		r = fh
+	}
+
+	m, err := ReadMinCost(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	return m.Solve()
+}
+
+// mcfEdge is an arc of the residual graph; rev is the index of the
+// reverse arc in the list of node 'to'.
+type mcfEdge struct {
+	to        int
+	cap, cost int64
+	rev       int
+}
+
+// Solve returns a minimum cost flow that meets the supplies and demands
+// and the arc bounds, or an error if there is none.
+//
+// Lower bounds are removed by shipping them up front and arcs with a
+// negative cost are saturated, so that the residual graph starts without
+// negative costs; the remaining supply is then sent from a super source to a
+// super sink along shortest paths found by Dijkstra with node potentials.
+func (m *MinCostNetwork) Solve() (*MinCostSolution, error) {
+	n := int(m.numNodes)
+	s, t := n, n+1
+	g := make([][]mcfEdge, n+2)
+	addEdge := func(from, to int, cap, cost int64) (int, int) {
+		g[from] = append(g[from], mcfEdge{to: to, cap: cap, cost: cost, rev: len(g[to])})
+		g[to] = append(g[to], mcfEdge{to: from, cap: 0, cost: -cost, rev: len(g[from]) - 1})
+		return from, len(g[from]) - 1
+	}
+
+	supply := make([]int64, n)
+	copy(supply, m.supply)
+	var total int64
+	for i, v := range supply {
+		var ok bool
+		if total, ok = addInt64(total, v); !ok {
+			return nil, fmt.Errorf("total supply overflows int64 at node %d", i+1)
+		}
+	}
+	if total != 0 {
+		return nil, fmt.Errorf("supplies and demands do not balance: net supply %d", total)
+	}
+
+	var cost int64
+	// ship sends flow along an arc up front, moving supply from node
+	// 'from' to node 'to' at unitCost, and reports whether it fits in int64.
+	ship := func(from, to int, flow, unitCost int64) bool {
+		var okFrom, okTo, okCost bool
+		supply[from], okFrom = addInt64(supply[from], -flow)
+		supply[to], okTo = addInt64(supply[to], flow)
+		c, okMul := mulInt64(flow, unitCost)
+		cost, okCost = addInt64(cost, c)
+		return okFrom && okTo && okMul && okCost && flow != math.MinInt64
+	}
+	pos := make([][2]int, len(m.arcs))
+	for i, a := range m.arcs {
+		if a.Low > a.High {
+			return nil, fmt.Errorf("arc %d (%d, %d) has lower bound %d above upper bound %d",
+				i, a.From, a.To, a.Low, a.High)
+		}
+		from, to := int(a.From-1), int(a.To-1)
+		residual, ok := addInt64(a.High, -a.Low)
+		if !ok || !ship(from, to, a.Low, a.Cost) {
+			return nil, fmt.Errorf("flow or cost overflows int64 at arc %d (%d, %d)", i, a.From, a.To)
+		}
+
+		u, k := addEdge(from, to, residual, a.Cost)
+		pos[i] = [2]int{u, k}
+		if a.Cost < 0 {
+			e := &g[u][k]
+			g[e.to][e.rev].cap = e.cap
+			if !ship(from, to, e.cap, a.Cost) {
+				return nil, fmt.Errorf("flow or cost overflows int64 at arc %d (%d, %d)", i, a.From, a.To)
+			}
+			e.cap = 0
+		}
+	}
+
+	var need int64
+	for i, v := range supply {
+		ok := v != math.MinInt64
+		if v > 0 {
+			addEdge(s, i, v, 0)
+			need, ok = addInt64(need, v)
+		} else if v < 0 {
+			addEdge(i, t, -v, 0)
+		}
+		if !ok {
+			return nil, fmt.Errorf("total supply overflows int64 at node %d", i+1)
+		}
+	}
+
+	potential := make([]int64, n+2)
+	dist := make([]int64, n+2)
+	prev := make([][2]int, n+2)
+	done := make([]bool, n+2)
+	for need > 0 {
+		if !shortestPaths(g, s, potential, dist, prev, done) || !done[t] {
+			return nil, fmt.Errorf("infeasible: %d units of supply cannot be shipped", need)
+		}
+		// Nodes not reached get the largest distance, which keeps the
+		// reduced cost of their arcs into reached nodes non-negative.
+		var maxDist int64
+		for v := range g {
+			if done[v] && dist[v] > maxDist {
+				maxDist = dist[v]
+			}
+		}
+		for v := range g {
+			if done[v] {
+				potential[v] += dist[v]
+			} else {
+				potential[v] += maxDist
+			}
+		}
+
+		push := need
+		for v := t; v != s; v = prev[v][0] {
+			if c := g[prev[v][0]][prev[v][1]].cap; c < push {
+				push = c
+			}
+		}
+		for v := t; v != s; v = prev[v][0] {
+			e := &g[prev[v][0]][prev[v][1]]
+			e.cap -= push
+			g[e.to][e.rev].cap += push
+			c, ok := mulInt64(push, e.cost)
+			if ok {
+				cost, ok = addInt64(cost, c)
+			}
+			if !ok {
+				return nil, fmt.Errorf("cost overflows int64")
+			}
+		}
+		need -= push
+	}
+
+	sol := &MinCostSolution{Cost: cost, Arcs: make([]MinCostArc, len(m.arcs))}
+	for i, a := range m.arcs {
+		e := g[pos[i][0]][pos[i][1]]
+		a.Flow = a.High - e.cap
+		sol.Arcs[i] = a
+	}
+
+	return sol, nil
+}
+
+// addInt64 and mulInt64 return x+y and x*y and whether they fit in an int64.
+func addInt64(x, y int64) (int64, bool) {
+	z := x + y
+	return z, (z > x) == (y > 0)
+}
+
+func mulInt64(x, y int64) (int64, bool) {
+	if x == 0 || y == 0 {
+		return 0, true
+	}
+	z := x * y
+	return z, z/y == x && !(x == math.MinInt64 && y == -1)
+}
+
+// shortestPaths runs Dijkstra from s on the arcs of g with residual capacity,
+// using reduced costs cost + potential[from] - potential[to], which are
+// non-negative. It fills dist, the arc into each node in prev and the nodes
+// reached in done, and reports whether any node other than s was reached.
+func shortestPaths(g [][]mcfEdge, s int, potential, dist []int64, prev [][2]int, done []bool) bool {
+	for v := range done {
+		done[v] = false
+		dist[v] = math.MaxInt64
+	}
+	dist[s] = 0
+	pq := &mcfQueue{{node: s}}
+	reached := false
+
+	for pq.Len() > 0 {
+		item := heap.Pop(pq).(mcfItem)
+		u := item.node
+		if done[u] {
+			continue
+		}
+		done[u] = true
+		reached = reached || u != s
+		for k, e := range g[u] {
+			if e.cap <= 0 || done[e.to] {
+				continue
+			}
+			d := dist[u] + e.cost + potential[u] - potential[e.to]
+			if d < dist[e.to] {
+				dist[e.to] = d
+				prev[e.to] = [2]int{u, k}
+				heap.Push(pq, mcfItem{node: e.to, dist: d})
+			}
+		}
+	}
+
+	return reached
+}
+
+// mcfQueue is a priority queue of nodes by tentative distance for shortestPaths.
+type mcfItem struct {
+	node int
+	dist int64
+}
+
+type mcfQueue []mcfItem
+
+func (q mcfQueue) Len() int            { return len(q) }
+func (q mcfQueue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q mcfQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *mcfQueue) Push(x interface{}) { *q = append(*q, x.(mcfItem)) }
+func (q *mcfQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// Dimacs returns the MinCostSolution in DIMACS syntax: the cost on an "s" line
+// and the flow on each arc on an "f" line.
+//
+// Example for examples/dimacsMcf.txt:
+//
+//	c <header>
+//	c
+//	c Dimacs-format minimum cost flow result file
+//	c generated by pseudo.go
+//	c
+//	s 14
+//	c
+//	c SRC DST FLOW
+//	f 1 2 2
+//	...
+func (s *MinCostSolution) Dimacs(header string) []string {
+	ret := []string{
+		"c " + header,
+		"c ",
+		"c Dimacs-format minimum cost flow result file",
+		"c generated by pseudo.go",
+		"c ",
+		fmt.Sprintf("s %d", s.Cost),
+		"c ",
+		"c SRC DST FLOW",
+	}
+	for _, a := range s.Arcs {
+		ret = append(ret, fmt.Sprintf("f %d %d %d", a.From, a.To, a.Flow))
+	}
+
+	return ret
+}
@@ -0,0 +1,279 @@
+// network.go - build a Network in memory rather than reading a DIMACS file.
+
+package pseudo
+
+import (
+	"fmt"
+	"math"
+)
+
+// ArcID identifies an arc of a Network. Arcs are numbered from 0 in the
+// order they are added, whether by AddArc or from the "a" lines read by
+// ReadDimacsFile.
+type ArcID uint
+
+// NewNetwork returns a Network with nodes numbered 1 to numNodes and no arcs.
+// The network is completed with AddArc, SetSource and SetSink, after which it
+// is solved just like a Network read by ReadDimacsFile. The Context of the
+// returned Network is PseudoCtx, and its Logger PseudoLogger.
+//
+// Example:
+//
+//	nw := pseudo.NewNetwork(4)
+//	nw.SetSource(1)
+//	nw.SetSink(4)
+//	nw.AddArc(1, 2, 5)
+//	nw.AddArc(2, 4, 3)
+//	...
+//	if err := nw.SimpleInitialization(); err != nil {
+//		...
+//	}
+//	nw.FlowPhaseOne()
+//	nw.RecoverFlow()
+func NewNetwork(numNodes uint) *Network {
+	return NewNetworkOf[int64](numNodes)
+}
+
+// NewNetworkOf is NewNetwork for capacities of type T. A NetworkOf[float64]
+// has an Epsilon of DefaultEpsilon.
+//
+// Example:
+//
+//	nw := pseudo.NewNetworkOf[float64](4)
+//	nw.SetSource(1)
+//	nw.SetSink(4)
+//	nw.AddArc(1, 2, 0.25)
+//	...
+//	sol, err := nw.Solve()
+func NewNetworkOf[T Capacity](numNodes uint) *NetworkOf[T] {
+	nw := &NetworkOf[T]{Ctx: PseudoCtx, Logger: PseudoLogger}
+	nw.defaultEpsilon()
+	nw.init(numNodes, 0)
+	return nw
+}
+
+// AddArc adds an arc from node 'from' to node 'to' with the given capacity
+// and returns its ArcID. It panics if either node is not in the Network.
+// A negative capacity is reported when the Network is built.
+//
+// On a Network that has been initialized the arc joins the normalized tree of
+// the last solve, saturated if it runs out of the source or into the sink, as
+// SimpleInitialization would have it, and otherwise without flow; the next
+// Solve or SolveMinCut continues from there as after UpdateCapacity. An arc
+// whose capacity could not have been built returns the Network to its state
+// before SimpleInitialization, so that the next solve reports it.
+func (nw *NetworkOf[T]) AddArc(from, to uint, capacity T) ArcID {
+	if from < 1 || from > nw.numNodes || to < 1 || to > nw.numNodes {
+		panic(fmt.Sprintf("pseudo: AddArc(%d, %d): node out of range 1..%d", from, to, nw.numNodes))
+	}
+	if !nw.initialized {
+		return nw.addArc(from, to, capacity)
+	}
+
+	if !nw.beginUpdate() {
+		return nw.addArc(from, to, capacity)
+	}
+	id := nw.addArc(from, to, capacity)
+	nw.insertArc(nw.arcs[id])
+	return id
+}
+
+// AddNode adds a node to the Network, with no arcs, and returns its number,
+// which is one more than the last. Like AddArc it may be called after a solve.
+func (nw *NetworkOf[T]) AddNode() uint {
+	if nw.initialized {
+		nw.beginUpdate()
+	}
+	n := &node[T]{number: nw.numNodes + 1}
+	nw.adjacencyList = append(nw.adjacencyList, n)
+	nw.labelCount = append(nw.labelCount, 0)
+	nw.strongRoots = append(nw.strongRoots, new(root[T]))
+	if nw.incident != nil {
+		nw.incident = append(nw.incident, nil)
+	}
+	nw.numNodes++
+	if nw.initialized {
+		// the nodes lifted over the gap, and the source, stay above all
+		// labels; the bucket of lifted roots becomes that of their old label
+		for _, m := range nw.adjacencyList[:nw.numNodes-1] {
+			if m.label == nw.numNodes-1 {
+				m.label = nw.numNodes
+			}
+		}
+		*nw.strongRoots[nw.numNodes-1] = root[T]{}
+		nw.adjacencyList[nw.source-1].label = nw.numNodes
+		// a weak root at label 0
+		nw.labelCount[0]++
+	}
+	return nw.numNodes
+}
+
+// SetSource sets the source node. It panics if n is not in the Network.
+func (nw *NetworkOf[T]) SetSource(n uint) {
+	nw.checkNode("SetSource", n)
+	nw.source = n
+}
+
+// SetSink sets the sink node. It panics if n is not in the Network.
+func (nw *NetworkOf[T]) SetSink(n uint) {
+	nw.checkNode("SetSink", n)
+	nw.sink = n
+}
+
+// NumNodes returns the number of nodes of the Network.
+func (nw *NetworkOf[T]) NumNodes() uint {
+	return nw.numNodes
+}
+
+// NumArcs returns the number of arcs of the Network.
+func (nw *NetworkOf[T]) NumArcs() uint {
+	return uint(len(nw.arcs))
+}
+
+func (nw *NetworkOf[T]) checkNode(fn string, n uint) {
+	if n < 1 || n > nw.numNodes {
+		panic(fmt.Sprintf("pseudo: %s(%d): node out of range 1..%d", fn, n, nw.numNodes))
+	}
+}
+
+// init allocates the node structures for numNodes nodes; numArcs is a
+// hint of the number of arcs that will be added.
+// This is the "p" line processing of readDimacsFileCreateList in C source code.
+func (nw *NetworkOf[T]) init(numNodes, numArcs uint) {
+	nw.numNodes = numNodes
+	nw.lowestStrongLabel = 1
+	nw.highestStrongLabel = 1
+	nw.adjacencyList = make([]*node[T], numNodes)
+	nw.strongRoots = make([]*root[T], numNodes+1) // strongRoots[numNodes] holds lifted roots split by addExcess
+	nw.labelCount = make([]uint, numNodes)
+	nw.arcs = make([]*arc[T], 0, numArcs)
+
+	nodes := make([]node[T], numNodes)
+	roots := make([]root[T], numNodes+1)
+	var i uint
+	for i = 0; i < numNodes; i++ {
+		nw.strongRoots[i] = &roots[i]
+		nodes[i].number = i + 1
+		nw.adjacencyList[i] = &nodes[i]
+	}
+	nw.strongRoots[numNodes] = &roots[numNodes]
+}
+
+// arcSlabSize is the number of arcs addArc allocates at a time.
+const arcSlabSize = 1024
+
+// addArc is the "a" line processing of readDimacsFileCreateList in C source code.
+// Arcs are kept in input order until build places them in arcList.
+func (nw *NetworkOf[T]) addArc(from, to uint, capacity T) ArcID {
+	id := ArcID(len(nw.arcs))
+	if len(nw.arcSlab) == 0 {
+		nw.arcSlab = make([]arc[T], arcSlabSize)
+	}
+	a := &nw.arcSlab[0]
+	nw.arcSlab = nw.arcSlab[1:]
+	*a = arc[T]{
+		from:      nw.adjacencyList[from-1],
+		to:        nw.adjacencyList[to-1],
+		capacity:  capacity,
+		direction: 1,
+		id:        id,
+	}
+	nw.arcs = append(nw.arcs, a)
+	if nw.incident != nil {
+		nw.incident[from-1] = append(nw.incident[from-1], uint32(id))
+		nw.incident[to-1] = append(nw.incident[to-1], uint32(id))
+	}
+	nw.adjacencyList[from-1].numAdjacent++
+	nw.adjacencyList[to-1].numAdjacent++
+	nw.built = false
+	return id
+}
+
+// build fills arcList and the out-of-tree arc lists from the arcs added so far.
+// As in readDimacsFileCreateList of C source code, arcs with odd (from+to) fill
+// arcList from the front and the others fill it from the back.
+// Capacities must be non-negative and their total must fit in T,
+// which bounds every excess and flow of the solve.
+func (nw *NetworkOf[T]) build() error {
+	if nw.source < 1 || nw.source > nw.numNodes {
+		return fmt.Errorf("no source node")
+	}
+	if nw.sink < 1 || nw.sink > nw.numNodes {
+		return fmt.Errorf("no sink node")
+	}
+	if nw.source == nw.sink {
+		return fmt.Errorf("source and sink are both node %d", nw.source)
+	}
+
+	var total T
+	maxTotal := maxCapacity[T]()
+	for id, a := range nw.arcs {
+		if a.capacity != a.capacity {
+			return fmt.Errorf("arc %d (%d, %d) has capacity NaN",
+				id, a.from.number, a.to.number)
+		}
+		if a.capacity < 0 {
+			return fmt.Errorf("arc %d (%d, %d) has negative capacity %v",
+				id, a.from.number, a.to.number, a.capacity)
+		}
+		if total > maxTotal-a.capacity {
+			return fmt.Errorf("total arc capacity overflows %T at arc %d (%d, %d)",
+				total, id, a.from.number, a.to.number)
+		}
+		total += a.capacity
+	}
+	nw.total = total
+
+	var i, from, to uint
+	var capacity T
+	nw.numArcs = uint(len(nw.arcs))
+	nw.arcList = make([]*arc[T], nw.numArcs)
+	first, last := uint(0), nw.numArcs
+	for _, a := range nw.arcs {
+		if (a.from.number+a.to.number)%2 != 0 {
+			nw.arcList[first] = a
+			first++
+		} else {
+			last--
+			nw.arcList[last] = a
+		}
+	}
+
+	for i = 0; i < nw.numNodes; i++ {
+		nw.adjacencyList[i].numberOutOfTree = 0
+		nw.adjacencyList[i].createOutOfTree()
+	}
+
+	for i = 0; i < nw.numArcs; i++ {
+		to = nw.arcList[i].to.number
+		from = nw.arcList[i].from.number
+		capacity = nw.arcList[i].capacity
+
+		if !(nw.source == to || nw.sink == from || from == to) {
+			if nw.source == from && to == nw.sink {
+				nw.arcList[i].flow = capacity
+			} else if from == nw.source || to != nw.sink {
+				nw.adjacencyList[from-1].addOutOfTreeNode(nw.arcList[i])
+			} else if to == nw.sink {
+				nw.adjacencyList[to-1].addOutOfTreeNode(nw.arcList[i])
+			} else {
+				nw.adjacencyList[from-1].addOutOfTreeNode(nw.arcList[i])
+			}
+		}
+	}
+
+	nw.built = true
+	return nil
+}
+
+// maxCapacity returns the largest value of T.
+func maxCapacity[T Capacity]() T {
+	var max T
+	switch p := any(&max).(type) {
+	case *int64:
+		*p = math.MaxInt64
+	case *float64:
+		*p = math.MaxFloat64
+	}
+	return max
+}
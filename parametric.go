@@ -0,0 +1,380 @@
+// parametric.go - parametric min cut over a sweep of lambda values.
+
+package pseudo
+
+import (
+	"fmt"
+	"time"
+)
+
+// BreakpointOf is a minimum cut of a parametric NetworkOf[T]; see SolveParametric.
+type BreakpointOf[T Capacity] struct {
+	// Lambda is the least lambda, to float64 precision, from which Cut is
+	// a minimum cut, up to the Lambda of the next Breakpoint; the first
+	// Breakpoint is at the first lambda of the search. Where minimum cuts
+	// tie, the search takes the one of the largest source set it finds.
+	Lambda float64
+	// Cut is the minimum cut at Lambda.
+	*CutOf[T]
+}
+
+// Breakpoint is a minimum cut of a parametric Network.
+type Breakpoint = BreakpointOf[int64]
+
+// SolveParametric finds the minimum cuts of nw as the capacities of the arcs
+// out of the source and into the sink vary with a parameter lambda, over the
+// range from the first to the last of lambdas, which must be increasing.
+// capacity(id, lambda) is the capacity of each such arc: it must not
+// decrease with lambda for arcs out of the source, an arc to the sink
+// included, nor increase for arcs into the sink. The source sets of the
+// minimum cuts then grow with lambda, and SolveParametric returns all the
+// breakpoints of the range: a Breakpoint for the first lambda and one for
+// each lambda at which the source set changes, located to adjacent float64
+// values.
+//
+// The network is solved once for lambdas, by SimpleInitialization and
+// FlowPhaseOne at the first of them. For each later one the change in
+// capacity of each arc is added to the excess of its node in the normalized
+// tree left by the previous FlowPhaseOne, and FlowPhaseOne continues from
+// there with the same labels. Where the source set changes between two of
+// lambdas, the breakpoints between them are found as by Gallo, Grigoriadis
+// and Tarjan: the difference of the capacities of the two cuts, being
+// monotone in lambda, is bisected for the lambda at which the larger source
+// set becomes minimum, and the network is solved there with the smaller
+// source set contracted into the source and the nodes outside the larger
+// one into the sink. That shows either the breakpoint or a source set in
+// between, which the search goes on with. lambdas need only hold the ends
+// of the range; more of them make the contracted networks smaller. The
+// capacities between them are checked for monotony only as far as the
+// search evaluates them.
+//
+// nw must have been read or constructed but not yet initialized. Afterwards
+// its capacities are those at the last lambda and RecoverFlow may be called
+// for the maximum flow at that value.
+func (nw *NetworkOf[T]) SolveParametric(lambdas []float64, capacity func(id ArcID, lambda float64) T) ([]BreakpointOf[T], error) {
+	if nw.initialized {
+		return nil, fmt.Errorf("SolveParametric on an initialized network")
+	}
+	if len(lambdas) == 0 {
+		return nil, nil
+	}
+	for i := 1; i < len(lambdas); i++ {
+		if !(lambdas[i] > lambdas[i-1]) {
+			return nil, fmt.Errorf("lambda %v does not increase from %v", lambdas[i], lambdas[i-1])
+		}
+	}
+	nw.startSolve()
+
+	var ids []ArcID
+	for id, a := range nw.arcs {
+		if nw.parametric(a) {
+			ids = append(ids, ArcID(id))
+			a.capacity = capacity(ArcID(id), lambdas[0])
+		}
+	}
+	if err := nw.SimpleInitialization(); err != nil {
+		return nil, err
+	}
+	nw.timer.initialize = time.Now()
+	nw.FlowPhaseOne()
+	cut := nw.MinCut()
+	p := &parametricSearch[T]{nw: nw, capacity: capacity, bps: []BreakpointOf[T]{{lambdas[0], cut}}}
+	last, lastSet := lambdas[0], p.sourceSet(cut)
+
+	maxTotal := maxCapacity[T]()
+	for _, lambda := range lambdas[1:] {
+		for _, id := range ids {
+			a := nw.arcs[id]
+			c := capacity(id, lambda)
+
+			var delta T
+			if a.from.number == nw.source {
+				delta = c - a.capacity
+			} else {
+				delta = a.capacity - c
+			}
+			if delta < 0 {
+				return nil, fmt.Errorf("arc %d (%d, %d): capacity %v at lambda %v is not monotone from %v",
+					id, a.from.number, a.to.number, c, lambda, a.capacity)
+			}
+			if c > a.capacity && nw.total > maxTotal-(c-a.capacity) {
+				return nil, fmt.Errorf("total arc capacity overflows %T at lambda %v", c, lambda)
+			}
+			nw.total += c - a.capacity
+			a.capacity = c
+			a.flow = c
+			switch {
+			case a.from.number == nw.source && a.to.number == nw.sink:
+				// in every cut
+			case a.from.number == nw.source:
+				nw.addExcess(a.to, delta)
+			default:
+				nw.addExcess(a.from, delta)
+			}
+		}
+
+		if nw.Ctx.LowestLabel {
+			nw.lowestStrongLabel = 0
+		} else {
+			nw.highestStrongLabel = nw.numNodes - 1
+		}
+		nw.FlowPhaseOne()
+
+		// the source sets are nested, so a change shows in their size
+		cut := nw.MinCut()
+		if len(cut.SourceSet) != len(p.bps[len(p.bps)-1].SourceSet) {
+			set := p.sourceSet(cut)
+			// the union is a minimum cut too, should FlowPhaseOne have
+			// found one that is not nested
+			for i, in := range lastSet {
+				set[i] = set[i] || in
+			}
+			if err := p.between(last, lastSet, lambda, set); err != nil {
+				return nil, err
+			}
+			lastSet = set
+		}
+		last = lambda
+	}
+	nw.timer.flow = time.Now()
+	nw.timer.recflow = nw.timer.flow
+
+	return p.bps, nil
+}
+
+// parametricSearch finds the breakpoints of SolveParametric between the
+// lambdas of its sweep. Source sets are indexed by node number - 1.
+type parametricSearch[T Capacity] struct {
+	nw       *NetworkOf[T]
+	capacity func(id ArcID, lambda float64) T
+	bps      []BreakpointOf[T]
+}
+
+// contractedArc is an arc of the network solved by probe: 1 is its source,
+// 2 its sink and the other nodes are numbered from 3.
+type contractedArc[T Capacity] struct {
+	a        *arc[T]
+	from, to uint
+}
+
+// sourceSet returns the source set of cut.
+func (p *parametricSearch[T]) sourceSet(cut *CutOf[T]) []bool {
+	set := make([]bool, p.nw.numNodes)
+	for _, n := range cut.SourceSet {
+		set[n-1] = true
+	}
+	return set
+}
+
+// capacityAt returns the capacity of a at lambda.
+func (p *parametricSearch[T]) capacityAt(a *arc[T], lambda float64) T {
+	if p.nw.parametric(a) {
+		return p.capacity(a.id, lambda)
+	}
+	return a.capacity
+}
+
+// add appends the breakpoint of source set set at lambda, in place of the
+// last one if that is at lambda too.
+func (p *parametricSearch[T]) add(lambda float64, set []bool) {
+	cut := new(CutOf[T])
+	for i, in := range set {
+		if in {
+			cut.SourceSet = append(cut.SourceSet, uint(i+1))
+		}
+	}
+	for id, a := range p.nw.arcs {
+		if set[a.from.number-1] && !set[a.to.number-1] {
+			c := p.capacityAt(a, lambda)
+			cut.Arcs = append(cut.Arcs, CutArcOf[T]{ID: ArcID(id), From: a.from.number, To: a.to.number, Capacity: c})
+			cut.Capacity += c
+		}
+	}
+	if last := &p.bps[len(p.bps)-1]; last.Lambda == lambda {
+		last.CutOf = cut
+		return
+	}
+	p.bps = append(p.bps, BreakpointOf[T]{lambda, cut})
+}
+
+// between adds the breakpoints from lambda a, where sa is a minimum source
+// set, to b, where sb is, sa being within sb.
+func (p *parametricSearch[T]) between(a float64, sa []bool, b float64, sb []bool) error {
+	nw := p.nw
+	// the nodes of sb not in sa, and the arcs at them, sa contracted into
+	// the source and the nodes outside sb into the sink
+	var middle []*node[T]
+	number := make(map[*node[T]]uint)
+	for i, in := range sb {
+		if in && !sa[i] {
+			n := nw.adjacencyList[i]
+			number[n] = uint(len(middle) + 3)
+			middle = append(middle, n)
+		}
+	}
+	contract := func(n *node[T]) uint {
+		if k, ok := number[n]; ok {
+			return k
+		}
+		if sa[n.number-1] {
+			return 1
+		}
+		return 2
+	}
+	var arcs []contractedArc[T]
+	incident := nw.incidence()
+	for _, n := range middle {
+		for _, i := range incident[n.number-1] {
+			a := nw.arcs[i]
+			from, to := contract(a.from), contract(a.to)
+			// once, at a.from if it is in the middle
+			if (a.from != n && from > 2) || from == to || to == 1 || from == 2 {
+				continue
+			}
+			arcs = append(arcs, contractedArc[T]{a, from, to})
+		}
+	}
+
+	// the capacities of the cuts of sa and of sb, but for the arcs from
+	// sa to the nodes outside sb, which both have
+	cuts := func(lambda float64) (ca, cb T) {
+		for _, c := range arcs {
+			if c.from == 1 {
+				ca += p.capacityAt(c.a, lambda)
+			}
+			if c.to == 2 {
+				cb += p.capacityAt(c.a, lambda)
+			}
+		}
+		return ca, cb
+	}
+	// probe returns the largest minimum source set at lambda, that of the
+	// nodes that cannot reach the sink in the residual network, and the
+	// amount by which its cut is less than that of sa and that of sb
+	probe := func(lambda float64) ([]bool, T, T, error) {
+		sub := NewNetworkOf[T](uint(len(middle) + 2))
+		sub.Ctx, sub.Epsilon, sub.Logger = nw.Ctx, nw.Epsilon, nil
+		sub.SetSource(1)
+		sub.SetSink(2)
+		for _, c := range arcs {
+			sub.AddArc(c.from, c.to, p.capacityAt(c.a, lambda))
+		}
+		sol, err := sub.Solve()
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("lambda %v: %w", lambda, err)
+		}
+		at := make([][]ArcFlowOf[T], len(middle)+3)
+		for _, a := range sol.Arcs {
+			at[a.To] = append(at[a.To], a)
+			at[a.From] = append(at[a.From], a)
+		}
+		sink := make([]bool, len(middle)+3)
+		sink[2] = true
+		for queue := []uint{2}; len(queue) > 0; queue = queue[1:] {
+			for _, a := range at[queue[0]] {
+				if a.To == queue[0] && !sink[a.From] && nw.positive(a.Capacity-a.Flow) {
+					sink[a.From] = true
+					queue = append(queue, a.From)
+				}
+				if a.From == queue[0] && !sink[a.To] && nw.positive(a.Flow) {
+					sink[a.To] = true
+					queue = append(queue, a.To)
+				}
+			}
+		}
+		set := append([]bool(nil), sa...)
+		for k, n := range middle {
+			if !sink[k+3] {
+				set[n.number-1] = true
+			}
+		}
+		ca, cb := cuts(lambda)
+		nw.logger().Debug("parametric probe", "lambda", lambda, "nodes", len(middle), "arcs", len(arcs), "cut", sol.Flow)
+		return set, ca - sol.Flow, cb - sol.Flow, nil
+	}
+	same := func(x, y []bool) bool {
+		for i := range x {
+			if x[i] != y[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	// the difference of the cuts of sb and sa does not increase with
+	// lambda; bisect for the least lambda at which sb is minimum
+	gain := func(lambda float64) T {
+		ca, cb := cuts(lambda)
+		return cb - ca
+	}
+	if !nw.positive(gain(a)) {
+		p.add(a, sb)
+		return nil
+	}
+	lo, hi := a, b
+	for {
+		mid := lo + (hi-lo)/2
+		if !(mid > lo && mid < hi) {
+			break
+		}
+		if nw.positive(gain(mid)) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	// A source set in between that is minimum somewhere before lo is
+	// minimum at lo, and one less than sb somewhere after hi is less at hi.
+	set, _, _, err := probe(lo)
+	if err != nil {
+		return err
+	}
+	if !same(set, sa) {
+		if err := p.between(a, sa, lo, set); err != nil {
+			return err
+		}
+		return p.between(lo, set, b, sb)
+	}
+	if hi < b {
+		set, _, below, err := probe(hi)
+		if err != nil {
+			return err
+		}
+		if nw.positive(below) {
+			p.add(hi, set)
+			return p.between(hi, set, b, sb)
+		}
+	}
+	p.add(hi, sb)
+	return nil
+}
+
+// parametric reports whether the capacity of a is set by SolveParametric:
+// a runs out of the source or into the sink and is used by build.
+func (nw *NetworkOf[T]) parametric(a *arc[T]) bool {
+	from, to := a.from.number, a.to.number
+	if nw.source == to || nw.sink == from || from == to {
+		return false
+	}
+	return from == nw.source || to == nw.sink
+}
+
+// addExcess adds delta > 0 to the excess of n, as when the flow on an arc
+// out of the source into n is raised or the flow on an arc from n into the
+// sink is cut, and restores the normalized tree: the excess of a non-root
+// is pushed towards its root, and a root that becomes strong is put in the
+// bucket of its label.
+func (nw *NetworkOf[T]) addExcess(n *node[T], delta T) {
+	if n.parent != nil {
+		n.excess += delta
+		nw.pushExcess(n)
+		return
+	}
+
+	prevEx := n.excess
+	n.excess += delta
+	if !nw.positive(prevEx) && nw.positive(n.excess) {
+		nw.addToStrongBucket(n, nw.strongRoots[n.label])
+	}
+}
@@ -0,0 +1,171 @@
+// progress.go - report the progress of a solve, as PROGRESS mode of C source main() does.
+
+package pseudo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Phase is a processing step of a solve.
+type Phase int
+
+const (
+	PhaseReadFile       Phase = iota // ReadDimacsFile
+	PhaseInitialization              // SimpleInitialization, or the restart of a solved Network
+	PhaseOne                         // FlowPhaseOne
+	PhaseRecoverFlow                 // RecoverFlow
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseReadFile:
+		return "reading file"
+	case PhaseInitialization:
+		return "initialization"
+	case PhaseOne:
+		return "phase 1"
+	case PhaseRecoverFlow:
+		return "flow recovery"
+	}
+	return fmt.Sprintf("Phase(%d)", int(p))
+}
+
+// Progress is the state of a solve reported to an Observer.
+type Progress struct {
+	Phase Phase
+	// StrongRoots is the number of strong roots still to be processed by
+	// FlowPhaseOne, and Label the lowest or the highest label among them,
+	// as set by Ctx.LowestLabel.
+	StrongRoots uint
+	Label       uint
+	// Nodes is the number of nodes whose excess RecoverFlow has returned
+	// to the source, of NumNodes.
+	Nodes, NumNodes uint
+	// Stats are the statistics of the solve so far.
+	Stats Statistics
+}
+
+// Observer receives the progress of a solve of a Network whose Observer
+// it is. The calls are made from the solving goroutine, which waits for them.
+type Observer interface {
+	// PhaseStarted is called as each Phase starts.
+	PhaseStarted(p Progress)
+	// Progress is called every ProgressInterval strong roots processed by
+	// FlowPhaseOne and every ProgressInterval flow paths of RecoverFlow.
+	Progress(p Progress)
+	// PhaseFinished is called as each Phase ends, unless the solve
+	// is stopped by its context.
+	PhaseFinished(p Progress)
+}
+
+// DefaultProgressInterval is the ProgressInterval of a Network that has none.
+const DefaultProgressInterval = 1 << 16
+
+// ProgressPrinter returns an Observer that prints the progress of a solve to
+// w as comment lines: the "c Finished ..." lines of PROGRESS mode in C source
+// main() and a line for each call of Progress.
+//
+// Example:
+//
+//	c Finished reading file.
+//	c Finished initialization.
+//	c phase 1: 1204 strong roots, label 3, 65536 pushes, 38211 mergers
+//	...
+//	c Finished phase 1.
+func ProgressPrinter(w io.Writer) Observer {
+	return progressPrinter{w}
+}
+
+type progressPrinter struct {
+	w io.Writer
+}
+
+func (pp progressPrinter) PhaseStarted(p Progress) {}
+
+func (pp progressPrinter) Progress(p Progress) {
+	if p.Phase == PhaseRecoverFlow {
+		fmt.Fprintf(pp.w, "c %s: %d of %d nodes, %d pushes, %d mergers\n",
+			p.Phase, p.Nodes, p.NumNodes, p.Stats.NumPushes, p.Stats.NumMergers)
+		return
+	}
+	fmt.Fprintf(pp.w, "c %s: %d strong roots, label %d, %d pushes, %d mergers\n",
+		p.Phase, p.StrongRoots, p.Label, p.Stats.NumPushes, p.Stats.NumMergers)
+}
+
+func (pp progressPrinter) PhaseFinished(p Progress) {
+	fmt.Fprintf(pp.w, "c Finished %s.\n", p.Phase)
+}
+
+// progress returns the Progress of nw in phase.
+func (nw *NetworkOf[T]) progress(phase Phase) Progress {
+	p := Progress{Phase: phase, NumNodes: nw.numNodes, Stats: nw.stats}
+	if phase != PhaseOne {
+		return p
+	}
+	if nw.Ctx.LowestLabel {
+		p.Label = nw.lowestStrongLabel
+	} else {
+		p.Label = nw.highestStrongLabel
+	}
+	p.StrongRoots = nw.numStrongRoots
+	return p
+}
+
+// logger returns the Logger of nw, or a Logger that discards everything.
+func (nw *NetworkOf[T]) logger() *slog.Logger {
+	if nw.Logger == nil {
+		return discardLogger
+	}
+	return nw.Logger
+}
+
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// startPhase and finishPhase report phase to the Observer and the Logger
+// of nw, if any.
+func (nw *NetworkOf[T]) startPhase(phase Phase) {
+	if nw.Logger != nil {
+		nw.Logger.Debug("phase started", "phase", phase.String())
+	}
+	if nw.Observer != nil {
+		nw.Observer.PhaseStarted(nw.progress(phase))
+	}
+}
+
+func (nw *NetworkOf[T]) finishPhase(phase Phase) {
+	if nw.Logger != nil {
+		nw.Logger.Debug("phase finished", "phase", phase.String(), "pushes", nw.stats.NumPushes,
+			"mergers", nw.stats.NumMergers, "relabels", nw.stats.NumRelabels)
+	}
+	if nw.Observer != nil {
+		nw.Observer.PhaseFinished(nw.progress(phase))
+	}
+}
+
+// checkpoint is called by FlowPhaseOne after each strong root and by
+// RecoverFlow after each flow path, count being the number so far.
+// It checks ctx every ctxInterval and reports progress every
+// ProgressInterval; nodes is the Progress.Nodes of RecoverFlow.
+func (nw *NetworkOf[T]) checkpoint(ctx context.Context, phase Phase, count, nodes uint) error {
+	if count%ctxInterval == 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if nw.Observer == nil {
+		return nil
+	}
+	interval := nw.ProgressInterval
+	if interval == 0 {
+		interval = DefaultProgressInterval
+	}
+	if count%interval == 0 {
+		p := nw.progress(phase)
+		p.Nodes = nodes
+		nw.Observer.Progress(p)
+	}
+	return nil
+}
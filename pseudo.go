@@ -15,27 +15,137 @@
 // the runtime context options, if desired. However it is also possible to call the
 // individual processing functions - ReadDimacsFile, SimpleInitialization, FlowPhaseOne,
 // RecoverFlow, Results - sequentially.
+//
+// The package-level processing functions share a single Network. To solve several
+// problems, possibly concurrently, call the same functions as methods on a Network
+// value for each problem. A Network has int64 capacities; NetworkOf[float64]
+// solves networks with real-valued capacities.
 package pseudo
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 )
 
-// global variables
-var lowestStrongLabel uint
-var highestStrongLabel uint
-var adjacencyList []*node
-var strongRoots []*root
-var arcList []*arc
-var labelCount []uint
-var numNodes, numArcs, source, sink uint
+// Capacity is the type of the arc capacities, and so of the flows and
+// excesses, of a NetworkOf.
+type Capacity interface {
+	int64 | float64
+}
+
+// DefaultEpsilon is the Epsilon of a NetworkOf[float64] returned by
+// NewNetworkOf, or read or initialized with an Epsilon of 0.
+const DefaultEpsilon = 1e-9
+
+// NetworkOf holds a flow network with capacities of type T and all of the
+// state of solving it. Independent values can be solved concurrently; a
+// single NetworkOf must not be shared between goroutines.
+//
+// The zero value is an empty network ready for ReadDimacsFile.
+type NetworkOf[T Capacity] struct {
+	// Ctx holds the runtime switches for this network.
+	Ctx Context
+	// Epsilon is the tolerance used when an excess or flow is compared to
+	// zero, or a flow to a capacity: values within Epsilon are taken as
+	// equal. Floating point capacities need a positive Epsilon that allows
+	// for the rounding accumulated over a solve: a NetworkOf[float64] with
+	// an Epsilon of 0 gets DefaultEpsilon when it is read or initialized.
+	// Integer capacities are compared exactly with the zero value.
+	Epsilon T
+	// Observer, if not nil, receives the progress of reading and solving,
+	// every ProgressInterval steps within a phase, or DefaultProgressInterval
+	// if it is 0.
+	Observer         Observer
+	ProgressInterval uint
+	// MaxNodes is the largest number of nodes that ReadDimacs and
+	// ReadBinary accept from the problem they read, DefaultMaxNodes if it
+	// is 0; the nodes are allocated before any of them has been read.
+	MaxNodes uint
+	// Logger, if not nil, receives debug level events of reading and
+	// solving; nothing is logged otherwise.
+	Logger *slog.Logger
+	// KeepPhaseOne has RecoverFlow keep the state of FlowPhaseOne that it
+	// overwrites, the flow on each arc and the trees of each node, so that
+	// a solve after UpdateCapacity, AddArc or AddNode can continue from it.
+	// The first such change sets it; set it before the first solve for
+	// that change to continue too.
+	KeepPhaseOne bool
+
+	lowestStrongLabel  uint
+	highestStrongLabel uint
+	adjacencyList      []*node[T]
+	strongRoots        []*root[T]
+	arcList            []*arc[T] // arcs placed by parity of (from+to), as in C source
+	arcs               []*arc[T] // arcs in the order they were added; index is ArcID
+	arcSlab            []arc[T]  // allocated, not yet added arcs
+	labelCount         []uint
+	numStrongRoots     uint // roots in the buckets below numNodes, for progress
+	numNodes, numArcs  uint
+	source, sink       uint
+	total              T // sum of the arc capacities
+	stats              Statistics
+	timer              timings
+	built, initialized bool
+	warm               bool              // FlowPhaseOne continues a previous solve; see startPhaseOne
+	recovered          bool              // RecoverFlow has run since FlowPhaseOne
+	solved             bool              // and finished; see WriteResult
+	saved              *phaseOneState[T] // state overwritten by RecoverFlow; see UpdateCapacity
+	changed            *changes[T]       // changes since FlowPhaseOne, for relabel
+	incident           [][]uint32        // arcs at each node, for relabel; see incidence
+}
+
+// Network is the NetworkOf integer capacities that is read from DIMACS
+// files by the package-level functions.
+type Network = NetworkOf[int64]
+
+// defaultEpsilon sets the Epsilon of a NetworkOf[float64] to DefaultEpsilon
+// if it is 0.
+func (nw *NetworkOf[T]) defaultEpsilon() {
+	if eps, ok := any(&nw.Epsilon).(*float64); ok && *eps == 0 {
+		*eps = DefaultEpsilon
+	}
+}
+
+// positive reports whether x is greater than zero by more than nw.Epsilon.
+func (nw *NetworkOf[T]) positive(x T) bool {
+	return x > nw.Epsilon
+}
+
+// negative reports whether x is less than zero by more than nw.Epsilon.
+func (nw *NetworkOf[T]) negative(x T) bool {
+	return x < -nw.Epsilon
+}
+
+// zero reports whether x is within nw.Epsilon of zero.
+func (nw *NetworkOf[T]) zero(x T) bool {
+	return !nw.positive(x) && !nw.negative(x)
+}
+
+// std is the Network used by the package-level functions.
+var (
+	std   = new(Network)
+	stdMu sync.Mutex
+)
+
+func getStd() *Network {
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	return std
+}
+
+func setStd(nw *Network) {
+	stdMu.Lock()
+	std = nw
+	stdMu.Unlock()
+}
 
 // local context
 
@@ -43,51 +153,74 @@ var numNodes, numArcs, source, sink uint
 type Context struct {
 	LowestLabel bool
 	FifoBucket  bool
+	DisplayCut  bool // include the source set of the min cut in Result
 	// Stats       bool // always collect stats, reporting just requires call to StatsJSON
 }
 
 // PseudoCtx can also be set from a file using Config, but
 // that requires importing github.com/clbanning/checkjson.
+// It is the Context used by the package-level functions.
 var PseudoCtx Context
 
+// PseudoLogger, if not nil, is the Logger of the Networks of the
+// package-level functions.
+var PseudoLogger *slog.Logger
+
 // ConfigJSON returns the runtime context settings as a JSON object.
 func ConfigJSON() string {
 	j, _ := json.Marshal(PseudoCtx)
 	return string(j)
 }
 
-// statistics
-type statistics struct {
+// ConfigJSON returns the runtime context settings of nw as a JSON object.
+func (nw *NetworkOf[T]) ConfigJSON() string {
+	j, _ := json.Marshal(nw.Ctx)
+	return string(j)
+}
+
+// Statistics are the counts of the basic operations of a solve. Each Solve,
+// SolveContext, SolveMinCut and SolveParametric counts from zero.
+type Statistics struct {
 	NumPushes   uint `json:"numPushes"`
 	NumMergers  uint `json:"numMergers"`
 	NumRelabels uint `json:"numRelabels"`
 	NumGaps     uint `json:"numGaps"`
 	NumArcScans uint `json:"numArcScans"`
+	// Unlike C source: 1 if the solve continued FlowPhaseOne from the
+	// last one after UpdateCapacity, AddArc or AddNode, and 1 if it then
+	// did not end in a minimum cut and was solved again from
+	// SimpleInitialization.
+	NumWarmStarts uint `json:"numWarmStarts"`
+	NumRestarts   uint `json:"numRestarts"`
 }
 
-var stats statistics
-
-// StatsJSON returns the runtime stats as a JSON object.
+// StatsJSON returns the runtime stats of the last package-level solve as a JSON object.
 func StatsJSON() string {
-	j, _ := json.Marshal(stats)
+	return getStd().StatsJSON()
+}
+
+// StatsJSON returns the runtime stats of nw as a JSON object.
+func (nw *NetworkOf[T]) StatsJSON() string {
+	j, _ := json.Marshal(nw.stats)
 	return string(j)
 }
 
 // ==================== the arc object
-type arc struct {
-	from      *node
-	to        *node
-	flow      uint
-	capacity  uint
+type arc[T Capacity] struct {
+	from      *node[T]
+	to        *node[T]
+	flow      T
+	capacity  T
 	direction uint
+	id        ArcID // index in the input, which arcList does not keep
 }
 
-// (*arc) pushUpward
+// (*Network) pushUpward. 'a' is 'currentArc' in C source.
 // static inline void
-func (a *arc) pushUpward(child *node, parent *node, resCap uint) {
+func (nw *NetworkOf[T]) pushUpward(a *arc[T], child *node[T], parent *node[T], resCap T) {
 
-	stats.NumPushes++
-	if resCap >= child.excess {
+	nw.stats.NumPushes++
+	if !nw.positive(child.excess - resCap) {
 		parent.excess += child.excess
 		a.flow += child.excess
 		child.excess = 0
@@ -95,29 +228,30 @@ func (a *arc) pushUpward(child *node, parent *node, resCap uint) {
 	}
 
 	a.direction = 0
-	parent.excess += resCap // int and uint
-	child.excess -= resCap  // int and uint
+	parent.excess += resCap
+	child.excess -= resCap
 	a.flow = a.capacity
 	parent.outOfTree[parent.numberOutOfTree] = a
 	parent.numberOutOfTree++
 	parent.breakRelationship(child)
-	if PseudoCtx.LowestLabel {
-		lowestStrongLabel = child.label
+	if nw.Ctx.LowestLabel {
+		nw.lowestStrongLabel = child.label
 	}
 
-	child.addToStrongBucket(strongRoots[child.label])
+	nw.addToStrongBucket(child, nw.strongRoots[child.label])
 }
 
-// (*arc) pushDownward
+// (*Network) pushDownward. 'a' is 'currentArc' in C source.
 //static inline void
-func (a *arc) pushDownward(child *node, parent *node, flow uint) {
+func (nw *NetworkOf[T]) pushDownward(a *arc[T], child *node[T], parent *node[T], flow T) {
 
-	stats.NumPushes++
+	nw.stats.NumPushes++
 
-	if flow >= child.excess {
+	if !nw.positive(child.excess - flow) {
 		parent.excess += child.excess
-		a.flow = child.excess
+		a.flow -= child.excess
 		child.excess = 0
+		return
 	}
 
 	a.direction = 1
@@ -127,155 +261,153 @@ func (a *arc) pushDownward(child *node, parent *node, flow uint) {
 	parent.outOfTree[parent.numberOutOfTree] = a
 	parent.numberOutOfTree++
 	parent.breakRelationship(child)
-	if PseudoCtx.LowestLabel {
-		lowestStrongLabel = child.label
+	if nw.Ctx.LowestLabel {
+		nw.lowestStrongLabel = child.label
 	}
 
-	child.addToStrongBucket(strongRoots[child.label])
+	nw.addToStrongBucket(child, nw.strongRoots[child.label])
 }
 
 //Initialize a new arc value.
 //in-lined
-//func newArc() *arc {
-//	return &arc{direction: 1}
+//func newArc() *arc[T] {
+//	return &arc[T]{direction: 1}
 //}
 
 // ==================== the node object
-type node struct {
+type node[T Capacity] struct {
 	visited         uint
 	numAdjacent     uint
 	number          uint
 	label           uint
-	excess          uint
-	parent          *node
-	childList       *node
-	nextScan        *node
+	excess          T // negative for a deficit
+	parent          *node[T]
+	childList       *node[T]
+	nextScan        *node[T]
 	numberOutOfTree uint
-	outOfTree       []*arc // was **Arc in C, looking at CreateOutOfTree, we're dealing with a pool of Arc's
+	outOfTree       []*arc[T] // was **Arc in C, looking at CreateOutOfTree, we're dealing with a pool of Arc's
 	nextArc         uint
-	arcToParent     *arc
-	next            *node
+	arcToParent     *arc[T]
+	next            *node[T]
 }
 
 // #ifdef LOWEST_LABEL
 // static Node *
 // getLowestStrongRoot (void)
-func getLowestStrongRoot() *node {
+func (nw *NetworkOf[T]) getLowestStrongRoot() *node[T] {
 	var i uint
-	var strongRoot *node
+	var strongRoot *node[T]
 
-	if lowestStrongLabel == 0 {
-		for strongRoots[0].start != nil {
-			strongRoot = strongRoots[0].start
-			strongRoots[0].start = strongRoot.next
+	if nw.lowestStrongLabel == 0 {
+		for nw.strongRoots[0].start != nil {
+			strongRoot = nw.strongRoots[0].start
+			nw.strongRoots[0].start = strongRoot.next
 			strongRoot.next = nil
-			strongRoot.label = uint(1)
-
-			labelCount[0]--
-			labelCount[1]++
-			stats.NumRelabels++
+			nw.numStrongRoots--
+			nw.raiseRoot(strongRoot)
 
-			strongRoot.addToStrongBucket(strongRoots[strongRoot.label])
+			nw.addToStrongBucket(strongRoot, nw.strongRoots[strongRoot.label])
 		}
-		lowestStrongLabel = 1
+		nw.lowestStrongLabel = 1
 	}
 
-	for i = lowestStrongLabel; i < numNodes; i++ {
-		if strongRoots[i].start != nil {
-			lowestStrongLabel = i
+	for i = nw.lowestStrongLabel; i < nw.numNodes; i++ {
+		if nw.strongRoots[i].start != nil {
+			nw.lowestStrongLabel = i
 
-			if labelCount[i-1] == 0 {
-				stats.NumGaps++
+			if nw.labelCount[i-1] == 0 {
+				nw.stats.NumGaps++
 				return nil
 			}
 
-			strongRoot = strongRoots[i].start
-			strongRoots[i].start = strongRoot.next
+			strongRoot = nw.strongRoots[i].start
+			nw.strongRoots[i].start = strongRoot.next
 			strongRoot.next = nil
+			nw.numStrongRoots--
 			return strongRoot
 		}
 	}
 
-	lowestStrongLabel = numNodes
+	nw.lowestStrongLabel = nw.numNodes
 	return nil
 }
 
 // static Node *
 // getHighestStrongRoot (void)
-func getHighestStrongRoot() *node {
+func (nw *NetworkOf[T]) getHighestStrongRoot() *node[T] {
 	var i uint
-	var strongRoot *node
+	var strongRoot *node[T]
 
-	for i = highestStrongLabel; i > 0; i-- {
-		if strongRoots[i].start != nil {
-			highestStrongLabel = i
+	for i = nw.highestStrongLabel; i > 0; i-- {
+		if nw.strongRoots[i].start != nil {
+			nw.highestStrongLabel = i
 
-			if labelCount[i-1] > 0 {
-				strongRoot = strongRoots[i].start
-				strongRoots[i].start = strongRoot.next
+			if nw.labelCount[i-1] > 0 {
+				strongRoot = nw.strongRoots[i].start
+				nw.strongRoots[i].start = strongRoot.next
 				strongRoot.next = nil
+				nw.numStrongRoots--
 				return strongRoot
 			}
 
-			for strongRoots[i].start != nil {
-				stats.NumGaps++
-				strongRoot = strongRoots[i].start
-				strongRoots[i].start = strongRoot.next
-				strongRoot.liftAll()
+			for nw.strongRoots[i].start != nil {
+				nw.stats.NumGaps++
+				strongRoot = nw.strongRoots[i].start
+				nw.strongRoots[i].start = strongRoot.next
+				nw.numStrongRoots--
+				nw.liftAll(strongRoot)
 			}
 		}
 	}
 
-	if strongRoots[0].start != nil {
+	if nw.strongRoots[0].start == nil {
 		return nil
 	}
 
-	for strongRoots[0].start != nil {
-		strongRoot = strongRoots[0].start
-		strongRoots[0].start = strongRoot.next
-		strongRoot.label = 1
-
-		labelCount[0]--
-		labelCount[1]++
-		stats.NumRelabels++
+	for nw.strongRoots[0].start != nil {
+		strongRoot = nw.strongRoots[0].start
+		nw.strongRoots[0].start = strongRoot.next
+		nw.numStrongRoots--
+		nw.raiseRoot(strongRoot)
 
-		strongRoot.addToStrongBucket(strongRoots[strongRoot.label])
+		nw.addToStrongBucket(strongRoot, nw.strongRoots[strongRoot.label])
 	}
 
-	highestStrongLabel = 1
+	nw.highestStrongLabel = 1
 
-	strongRoot = strongRoots[1].start
-	strongRoots[1].start = strongRoot.next
+	strongRoot = nw.strongRoots[1].start
+	nw.strongRoots[1].start = strongRoot.next
 	strongRoot.next = nil
+	nw.numStrongRoots--
 
 	return strongRoot
 }
 
-// (*node) createOutOfTree allocates arc's for adjacent nodes.
-func (n *node) createOutOfTree() {
-	n.outOfTree = make([]*arc, n.numAdjacent) // OK if '0' are allocated
+// (*node[T]) createOutOfTree allocates arc's for adjacent nodes.
+func (n *node[T]) createOutOfTree() {
+	n.outOfTree = make([]*arc[T], n.numAdjacent) // OK if '0' are allocated
 }
 
-// (*node) addOutOfTreenode
-func (n *node) addOutOfTreeNode(out *arc) {
+// (*node[T]) addOutOfTreenode
+func (n *node[T]) addOutOfTreeNode(out *arc[T]) {
 	n.outOfTree[n.numberOutOfTree] = out
 	n.numberOutOfTree++
 }
 
-// (*node) processRoot. 'n' is 'strongRoot' in C source
-func (n *node) processRoot() {
-	var temp, weakNode *node
-	var out *arc
+// (*Network) processRoot. 'n' is 'strongRoot' in C source
+func (nw *NetworkOf[T]) processRoot(n *node[T]) {
+	var temp, weakNode *node[T]
+	var out *arc[T]
 	strongNode := n
 	n.nextScan = n.childList
 
-	if out, weakNode = n.findWeakNode(); out != nil {
-		weakNode.merge(n, out)
-		n.pushExcess()
+	if out, weakNode = nw.findWeakNode(n); out != nil {
+		nw.merge(weakNode, n, out)
+		nw.pushExcess(n)
 		return
 	}
 
-	n.checkChildren()
+	nw.checkChildren(n)
 
 	for strongNode != nil {
 		for strongNode.nextScan != nil {
@@ -284,39 +416,39 @@ func (n *node) processRoot() {
 			strongNode = temp
 			strongNode.nextScan = strongNode.childList
 
-			if out, weakNode = strongNode.findWeakNode(); out != nil {
-				weakNode.merge(strongNode, out)
-				n.pushExcess()
+			if out, weakNode = nw.findWeakNode(strongNode); out != nil {
+				nw.merge(weakNode, strongNode, out)
+				nw.pushExcess(n)
 				return
 			}
 
-			strongNode.checkChildren()
+			nw.checkChildren(strongNode)
 		}
 
 		if strongNode = strongNode.parent; strongNode != nil {
-			strongNode.checkChildren()
+			nw.checkChildren(strongNode)
 		}
 	}
 
-	n.addToStrongBucket(strongRoots[n.label])
+	nw.addToStrongBucket(n, nw.strongRoots[n.label])
 
-	if !PseudoCtx.LowestLabel {
-		highestStrongLabel++
+	if !nw.Ctx.LowestLabel {
+		nw.highestStrongLabel++
 	}
 }
 
 // static void
 // merge (Node *parent, Node *child, Arc *newArc)
-// (*node) merge. 'n' is 'parent' in C source.
-func (n *node) merge(child *node, newArc *arc) {
-	var oldArc *arc
-	var oldParent *node
+// (*Network) merge. 'n' is 'parent' in C source.
+func (nw *NetworkOf[T]) merge(n *node[T], child *node[T], newArc *arc[T]) {
+	var oldArc *arc[T]
+	var oldParent *node[T]
 	current := child
 	newParent := n
 
-	stats.NumMergers++ // unlike C source always calc stats
+	nw.stats.NumMergers++ // unlike C source always calc stats
 
-	for current != nil {
+	for current.parent != nil {
 		oldArc = current.arcToParent
 		current.arcToParent = newArc
 		oldParent = current.parent
@@ -335,38 +467,38 @@ func (n *node) merge(child *node, newArc *arc) {
 
 // static void
 // pushExcess (Node *strongRoot)
-// (*node) pushExcess. 'n' is 'strongRoot' in C source.
-func (n *node) pushExcess() {
-	var current, parent *node
-	var arcToParent *arc
-	prevEx := uint(1)
+// (*Network) pushExcess. 'n' is 'strongRoot' in C source.
+func (nw *NetworkOf[T]) pushExcess(n *node[T]) {
+	var current, parent *node[T]
+	var arcToParent *arc[T]
+	prevEx := T(1)
 
-	for current = n; current.excess > 0 && current.parent != nil; current = parent {
+	for current = n; nw.positive(current.excess) && current.parent != nil; current = parent {
 		parent = current.parent
 		prevEx = parent.excess
 
 		arcToParent = current.arcToParent
 
 		if arcToParent.direction > 0 {
-			arcToParent.pushUpward(current, parent, arcToParent.capacity-arcToParent.flow)
+			nw.pushUpward(arcToParent, current, parent, arcToParent.capacity-arcToParent.flow)
 		} else {
-			arcToParent.pushDownward(current, parent, arcToParent.flow)
+			nw.pushDownward(arcToParent, current, parent, arcToParent.flow)
 		}
 	}
 
-	if current.excess > 0 && prevEx <= 0 {
-		if PseudoCtx.LowestLabel {
-			lowestStrongLabel = current.label
+	if nw.positive(current.excess) && !nw.positive(prevEx) {
+		if nw.Ctx.LowestLabel {
+			nw.lowestStrongLabel = current.label
 		}
-		current.addToStrongBucket(strongRoots[current.label])
+		nw.addToStrongBucket(current, nw.strongRoots[current.label])
 	}
 }
 
 // static inline void
 // breakRelationship (Node *oldParent, Node *child)
-// (*node) breakRelationship
-func (n *node) breakRelationship(child *node) {
-	var current *node
+// (*node[T]) breakRelationship
+func (n *node[T]) breakRelationship(child *node[T]) {
+	var current *node[T]
 	child.parent = nil
 
 	if n.childList == child {
@@ -376,16 +508,17 @@ func (n *node) breakRelationship(child *node) {
 	}
 
 	for current = n.childList; current.next != child; current = current.next {
-		current.next = child.next
-		child.next = nil
 	}
+
+	current.next = child.next
+	child.next = nil
 }
 
 // static inline int
 // addRelationship (Node *newParent, Node *child)
-// (*node) addRelationship
+// (*node[T]) addRelationship
 // CLB: implement as static void function, calling code ignores return value
-func (n *node) addRelationship(child *node) {
+func (n *node[T]) addRelationship(child *node[T]) {
 	child.parent = n
 	child.next = n.childList
 	n.childList = child
@@ -393,19 +526,19 @@ func (n *node) addRelationship(child *node) {
 
 // static Arc *
 // findWeakNode (Node *strongNode, Node **weakNode)
-// (*node) findWeakNode() (*arc, weakNode *node)
+// (*Network) findWeakNode(n *node[T]) (*arc[T], weakNode *node[T]). 'n' is 'strongNode' in C source.
 // CLB: avoid pointer-to-pointer handling by also returning computed weakNode
-func (n *node) findWeakNode() (*arc, *node) {
+func (nw *NetworkOf[T]) findWeakNode(n *node[T]) (*arc[T], *node[T]) {
 	var i, size uint
-	var out *arc
-	var weakNode *node
+	var out *arc[T]
+	var weakNode *node[T]
 
 	size = n.numberOutOfTree
 
 	for i = n.nextArc; i < size; i++ {
-		stats.NumArcScans++
-		if PseudoCtx.LowestLabel {
-			if n.outOfTree[i].to.label == lowestStrongLabel-1 {
+		nw.stats.NumArcScans++
+		if nw.Ctx.LowestLabel {
+			if n.outOfTree[i].to.label == nw.lowestStrongLabel-1 {
 				n.nextArc = i
 				out = n.outOfTree[i]
 				weakNode = out.to
@@ -413,7 +546,7 @@ func (n *node) findWeakNode() (*arc, *node) {
 				n.outOfTree[i] = n.outOfTree[n.numberOutOfTree]
 				return out, weakNode
 			}
-			if n.outOfTree[i].from.label == (lowestStrongLabel - 1) {
+			if n.outOfTree[i].from.label == (nw.lowestStrongLabel - 1) {
 				n.nextArc = i
 				out = n.outOfTree[i]
 				weakNode = out.from
@@ -422,7 +555,7 @@ func (n *node) findWeakNode() (*arc, *node) {
 				return out, weakNode
 			}
 		} else {
-			if n.outOfTree[i].to.label == (highestStrongLabel - 1) {
+			if n.outOfTree[i].to.label == (nw.highestStrongLabel - 1) {
 				n.nextArc = i
 				out = n.outOfTree[i]
 				weakNode = out.to
@@ -430,7 +563,7 @@ func (n *node) findWeakNode() (*arc, *node) {
 				n.outOfTree[i] = n.outOfTree[n.numberOutOfTree]
 				return out, weakNode
 			}
-			if n.outOfTree[i].from.label == (highestStrongLabel - 1) {
+			if n.outOfTree[i].from.label == (nw.highestStrongLabel - 1) {
 				n.nextArc = i
 				out = n.outOfTree[i]
 				weakNode = out.from
@@ -446,34 +579,36 @@ func (n *node) findWeakNode() (*arc, *node) {
 
 }
 
-// (*node) checkChildren
-func (n *node) checkChildren() {
+// (*Network) checkChildren. 'n' is 'curNode' in C source.
+func (nw *NetworkOf[T]) checkChildren(n *node[T]) {
 	for ; n.nextScan != nil; n.nextScan = n.nextScan.next {
 		if n.nextScan.label == n.label {
 			return
 		}
 	}
 
-	labelCount[n.label]--
+	nw.labelCount[n.label]--
 	n.label++
-	labelCount[n.label]++
+	nw.labelCount[n.label]++
 
-	stats.NumRelabels++ // Always collect stats
+	nw.stats.NumRelabels++ // Always collect stats
 
 	n.nextArc = 0
 }
 
 // static void
 // liftAll (Node *rootNode)
-// node.liftAll()
-func (n *node) liftAll() {
-	var temp *node
+// (*Network) liftAll. 'n' is 'rootNode' in C source.
+// A tree relabeled for UpdateCapacity may hold nodes already lifted, which
+// labelCount does not count.
+func (nw *NetworkOf[T]) liftAll(n *node[T]) {
+	var temp *node[T]
 	current := n
 
 	current.nextScan = current.childList
 
-	labelCount[current.label]--
-	current.label = numNodes
+	nw.labelCount[current.label]--
+	current.label = nw.numNodes
 
 	for ; current != nil; current = current.parent {
 		for current.nextScan != nil {
@@ -482,14 +617,46 @@ func (n *node) liftAll() {
 			current = temp
 			current.nextScan = current.childList
 
-			labelCount[current.label]--
-			current.label = numNodes
+			if current.label < nw.numNodes {
+				nw.labelCount[current.label]--
+			}
+			current.label = nw.numNodes
+		}
+	}
+}
+
+// raiseRoot relabels a strong root at label 0 to 1, with the nodes at label 0
+// below it. After SimpleInitialization only the root is at 0, but a tree
+// relabeled for UpdateCapacity may have more, and the labels of a tree must
+// not fall from a node to its children.
+func (nw *NetworkOf[T]) raiseRoot(n *node[T]) {
+	stack := []*node[T]{n}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		current.label = 1
+		nw.labelCount[0]--
+		nw.labelCount[1]++
+		nw.stats.NumRelabels++
+		for child := current.childList; child != nil; child = child.next {
+			if child.label == 0 {
+				stack = append(stack, child)
+			}
 		}
 	}
 }
 
-func (n *node) addToStrongBucket(rootBucket *root) {
-	if PseudoCtx.FifoBucket {
+// (*Network) addToStrongBucket. 'n' is 'newRoot' in C source.
+// Between UpdateCapacity, AddArc or AddNode and the next solve the roots are
+// left to relabel, as a root may turn weak and strong again meanwhile.
+func (nw *NetworkOf[T]) addToStrongBucket(n *node[T], rootBucket *root[T]) {
+	if nw.changed != nil {
+		return
+	}
+	if rootBucket != nw.strongRoots[nw.numNodes] {
+		nw.numStrongRoots++
+	}
+	if nw.Ctx.FifoBucket {
 		if rootBucket.start != nil {
 			rootBucket.end.next = n
 			rootBucket.end = n
@@ -508,7 +675,7 @@ func (n *node) addToStrongBucket(rootBucket *root) {
 
 // static void
 // sort (Node * current)
-func (n *node) sort() {
+func (n *node[T]) sort() {
 	if n.numberOutOfTree > uint(1) {
 		quickSort(n.outOfTree, 0, n.numberOutOfTree-1)
 	}
@@ -516,13 +683,13 @@ func (n *node) sort() {
 
 // static void
 // minisort (Node *current)
-func (n *node) minisort() {
+func (n *node[T]) minisort() {
 	temp := n.outOfTree[n.nextArc]
 	var i uint
 	size := n.numberOutOfTree
 	tempflow := temp.flow
 
-	for i := n.nextArc + 1; i < size && tempflow < n.outOfTree[i].flow; i++ {
+	for i = n.nextArc + 1; i < size && tempflow < n.outOfTree[i].flow; i++ {
 		n.outOfTree[i-1] = n.outOfTree[i]
 	}
 	n.outOfTree[i-1] = temp
@@ -530,15 +697,22 @@ func (n *node) minisort() {
 
 // static void
 // decompose (Node *excessNode, const uint source, uint *iteration)
-// CLB: would prefer node.decompose(source) iteration, but keep mainline logic the same
-//  node.decompose(source uint, interation *uint)
-func (n *node) decompose(source uint, iteration *uint) {
+// (*Network) decompose. 'n' is 'excessNode' in C source; source is nw.source.
+// Unlike C source, it reports false, changing nothing, where the path back
+// from n reaches a node with no flow left into it: the excess of n is then
+// not carried by the flow, as rounding may leave it with floating point
+// capacities, and is left to checkOptimality.
+func (nw *NetworkOf[T]) decompose(n *node[T], iteration *uint) bool {
+	source := nw.source
 	current := n
-	var tempArc *arc
+	var tempArc *arc[T]
 	bottleneck := n.excess
 
 	for ; current.number != source && current.visited < *iteration; current = tempArc.from {
 		current.visited = *iteration
+		if current.nextArc >= current.numberOutOfTree {
+			return false
+		}
 		tempArc = current.outOfTree[current.nextArc]
 
 		if tempArc.flow < bottleneck {
@@ -554,14 +728,14 @@ func (n *node) decompose(source uint, iteration *uint) {
 			tempArc = current.outOfTree[current.nextArc]
 			tempArc.flow -= bottleneck
 
-			if tempArc.flow != 0 {
+			if !nw.zero(tempArc.flow) {
 				current.minisort()
 			} else {
 				current.nextArc++
 			}
 			current = tempArc.from
 		}
-		return
+		return true
 	}
 
 	*iteration++
@@ -585,7 +759,7 @@ func (n *node) decompose(source uint, iteration *uint) {
 		tempArc = current.outOfTree[current.nextArc]
 		tempArc.flow -= bottleneck
 
-		if tempArc.flow != 0 {
+		if !nw.zero(tempArc.flow) {
 			current.minisort()
 			current = tempArc.from
 		} else {
@@ -593,13 +767,14 @@ func (n *node) decompose(source uint, iteration *uint) {
 			current = tempArc.from
 		}
 	}
+	return true
 }
 
 // =================== the root object
 // allocations are in-line, as needed
-type root struct {
-	start *node
-	end   *node
+type root[T Capacity] struct {
+	start *node[T]
+	end   *node[T]
 }
 
 // ================ results
@@ -607,77 +782,70 @@ type root struct {
 // static void
 // checkOptimality (const uint gap)
 // Internalize "gap" as in RecoverFlow.
-func checkOptimality() []string {
-	// setting gap value is taken out of main() in C source code
-	var gap uint
-	if PseudoCtx.LowestLabel {
-		gap = lowestStrongLabel
-	} else {
-		gap = numNodes
-	}
+// The outcome is returned rather than printed; see Solution.Dimacs.
+func (nw *NetworkOf[T]) checkOptimality() optimality[T] {
+	gap := nw.gap()
 
 	var i uint
-	var mincut uint
-	var ret []string
-	excess := make([]uint, numNodes)
+	var mincut T
+	var ret optimality[T]
+	excess := make([]T, nw.numNodes)
 
 	check := true
-	for i = 0; i < numArcs; i++ {
-		if arcList[i].from.label >= gap && arcList[i].to.label < gap {
-			mincut += arcList[i].capacity
+	for i = 0; i < nw.numArcs; i++ {
+		if nw.arcList[i].from.label >= gap && nw.arcList[i].to.label < gap {
+			mincut += nw.arcList[i].capacity
 		}
-		if arcList[i].flow > arcList[i].capacity || arcList[i].flow < 0 {
+		if nw.positive(nw.arcList[i].flow-nw.arcList[i].capacity) || nw.negative(nw.arcList[i].flow) {
 			check = false
-			ret = append(ret,
-				fmt.Sprintf("c Capacity constraint violated on arc (%d, %d). Flow = %d, capacity = %d",
-					arcList[i].from.number,
-					arcList[i].to.number,
-					arcList[i].flow,
-					arcList[i].capacity))
+			ret.violations = append(ret.violations,
+				fmt.Sprintf("Capacity constraint violated on arc (%d, %d). Flow = %v, capacity = %v",
+					nw.arcList[i].from.number,
+					nw.arcList[i].to.number,
+					nw.arcList[i].flow,
+					nw.arcList[i].capacity))
 		}
-		excess[arcList[i].from.number-1] -= arcList[i].flow
-		excess[arcList[i].to.number-1] += arcList[i].flow
+		excess[nw.arcList[i].from.number-1] -= nw.arcList[i].flow
+		excess[nw.arcList[i].to.number-1] += nw.arcList[i].flow
 	}
-	for i = 0; i < numNodes; i++ {
-		if i != source-1 && i != sink-1 {
-			if excess[i] != 0 {
+	for i = 0; i < nw.numNodes; i++ {
+		if i != nw.source-1 && i != nw.sink-1 {
+			if !nw.zero(excess[i]) {
 				check = false
-				ret = append(ret,
-					fmt.Sprintf("c Flow balance constraint violated in node %d. Excess = %d",
+				ret.violations = append(ret.violations,
+					fmt.Sprintf("Flow balance constraint violated in node %d. Excess = %v",
 						i+1,
 						excess[i]))
 			}
 		}
 	}
-	if check {
-		ret = append(ret, "c ", "c Solution checks as feasible")
-	}
-	fmt.Printf("Sink = %v", sink)
-	fmt.Printf("Sink -1=%v", sink-1)
-	check = true
-	if excess[sink-1] != mincut {
-		check = false
-		ret = append(ret, "c ", "c Flow is not optimal - max flow does not equal min cut")
-	}
-	if check {
-		ret = append(ret, "c ", "c Solution checks as optimal", "c Solution")
-		ret = append(ret, fmt.Sprintf("s %d\n", mincut))
-	}
+	ret.feasible = check
+	ret.flow = excess[nw.sink-1]
+	ret.mincut = mincut
+	ret.optimal = nw.zero(excess[nw.sink-1] - mincut)
+	nw.logger().Debug("checked optimality", "flow", ret.flow, "mincut", mincut,
+		"feasible", ret.feasible, "optimal", ret.optimal, "violations", len(ret.violations))
 
 	return ret
 }
 
+// optimality is the outcome of checkOptimality.
+type optimality[T Capacity] struct {
+	feasible, optimal bool
+	flow, mincut      T
+	violations        []string
+}
+
 // static void
 // displayFlow (void)
 // C_source uses "a SRC DST FLOW" format; however, the examples we have,
 // e.g., http://lpsolve.sourceforge.net/5.5/DIMACS_asn.htm, use
 // "f SRC DST FLOW" format.  Here we use the latter, since we can
 // then use the examples as test cases.
-func displayFlow() []string {
+func displayFlow[T Capacity](arcs []ArcFlowOf[T]) []string {
 	var ret []string
-	for i := uint(0); i < numArcs; i++ {
-		ret = append(ret,
-			fmt.Sprintf("f %d %d %d", arcList[i].from.number, arcList[i].to.number, arcList[i].flow))
+	for _, a := range arcs {
+		ret = append(ret, fmt.Sprintf("f %d %d %v", a.From, a.To, a.Flow))
 	}
 
 	return ret
@@ -695,254 +863,248 @@ func (a *AlphaString) Scan(state fmt.ScanState, verb rune) error {
 	return nil
 }
 
-// ReadDimacsFile implements readDimacsFile of C source code.
+// ReadDimacsFile reads fh into a new Network, with PseudoCtx as its
+// Context, that is then used by the other package-level functions.
+// The package-level functions must not be called from multiple goroutines;
+// use a Network value for each problem instead.
 func ReadDimacsFile(fh *os.File) error {
-	var i, numLines, from, to, first, last uint
-	var capacity uint
-	//var word []byte
-	var ch, word AlphaString
-	//var ch1 byte
-	var ch1 AlphaString
-	fmt.Println("Reading Dimacs\n")
-	buf := bufio.NewReader(fh)
-	var atEOF bool
-	for {
-		if atEOF {
-			break
-		}
-		fmt.Println("Reading line")
-		line, err := buf.ReadBytes('\n')
-		fmt.Println("Reading line 2")
-		if err != io.EOF {
-			fmt.Println("err!=io.EOF")
-			fmt.Print(err)
-			//return err
-		} else if err == io.EOF {
-			fmt.Println("EOF")
-			if len(line) == 0 {
-				fmt.Println("EOF2")
-				break // nothing more to process
-			}
-			// ... at EOF with data but no '\n' line termination.
-			// While not necessary for os.STDIN; it can happen in a file.
-			atEOF = true
-		} else {
-			// Strip off EOL.
-			fmt.Println("Strip EOL")
-			line = line[:len(line)-1]
-		}
-		fmt.Println("numLines++")
-		numLines++
-		fmt.Printf("line = %v", string(line))
-		fmt.Printf("line[0] = %v", string(line[0]))
-
-		switch line[0] {
-		case 'p':
-			fmt.Println("Case p ")
-			if _, err := fmt.Sscanf(string(line), "%v %s %d %d", &ch, &word, &numNodes, &numArcs); err != nil {
-				fmt.Println(err, ch, word, numNodes, numArcs)
-				return err
-			}
+	return ReadDimacs(fh)
+}
 
-			adjacencyList = make([]*node, numNodes)
-			strongRoots = make([]*root, numNodes)
-			labelCount = make([]uint, numNodes)
-			arcList = make([]*arc, numArcs)
-
-			var i uint
-			for i = 0; i < numNodes; i++ {
-				strongRoots[i] = new(root)
-				adjacencyList[i] = &node{number: i + 1}
-				var u uint
-				labelCount = append(labelCount, u)
-			}
-			for i = 0; i < numArcs; i++ {
-				arcList[i] = &arc{direction: 1}
-			}
-			first = 0
-			last = numArcs - 1
-		case 'a':
-			fmt.Println("Case a")
-			if _, err := fmt.Sscanf(string(line), "%v %d %d %d", &ch, &from, &to, &capacity); err != nil {
-				return err
-			}
-			if (from+to)%2 != 0 {
-				arcList[first].from = adjacencyList[from-1]
-				arcList[first].to = adjacencyList[to-1]
-				arcList[first].capacity = capacity
-				first++
-			} else {
-				arcList[last].from = adjacencyList[from-1]
-				arcList[last].to = adjacencyList[to-1]
-				arcList[last].capacity = capacity
-				last--
-			}
+// ReadDimacs is ReadDimacsFile for any io.Reader.
+func ReadDimacs(r io.Reader) error {
+	nw := &Network{Ctx: PseudoCtx, Logger: PseudoLogger}
+	setStd(nw)
+	return nw.ReadDimacs(r)
+}
 
-			adjacencyList[from-1].numAdjacent++
-			adjacencyList[to-1].numAdjacent++
-		case 'n':
-			fmt.Println("Case n")
-			if _, err := fmt.Sscanf(string(line), "%v %d %v", &ch, &i, &ch1); err != nil {
-				fmt.Println(err, ch, i, ch1)
-				return err
-			}
-			fmt.Println(ch, i, ch1)
-			//ch1 = string(ch1)
-			if ch1 == AlphaString('s') {
-				fmt.Println("Found a source")
-				source = i
-			} else if ch1 == AlphaString('t') {
-				fmt.Println("Found a sink")
-				sink = i
-			} else {
-				return fmt.Errorf("unrecognized character %v on line %d", ch1, numLines)
-			}
-		case '\n', 'c':
-			fmt.Println("Case newline or comment")
-			continue // catches blank lines and "comment" lines - blank lines not in spec.
-		default:
-			fmt.Println("Case default")
-			return fmt.Errorf("unknown data: %s", string(line))
-		}
+// ReadDimacsFile implements readDimacsFile of C source code.
+// Any previous network and solve state held by nw is discarded.
+func (nw *NetworkOf[T]) ReadDimacsFile(fh *os.File) error {
+	return nw.ReadDimacs(fh)
+}
+
+// ReadDimacs is ReadDimacsFile for any io.Reader. Input compressed with
+// gzip or bzip2 is decompressed. Input that is not a valid DIMACS max-flow
+// problem returns a *ParseError.
+func (nw *NetworkOf[T]) ReadDimacs(r io.Reader) error {
+	nw.discard()
+	nw.timer.start = time.Now()
+	nw.timer.read = true
+	nw.startPhase(PhaseReadFile)
+	log := nw.logger()
+	numLines, err := nw.parseDimacs(r, log)
+	if err != nil {
+		log.Debug("read failed", "err", err)
+		return err
 	}
 
-	for i = 0; i < numNodes; i++ {
-		adjacencyList[i].createOutOfTree()
+	err = nw.build()
+	nw.timer.readfile = time.Now()
+	if err == nil {
+		log.Debug("read network", "lines", numLines, "nodes", nw.numNodes, "arcs", nw.numArcs,
+			"source", nw.source, "sink", nw.sink)
+		nw.finishPhase(PhaseReadFile)
 	}
+	return err
+}
 
-	for i = 0; i < numArcs; i++ {
-		to = arcList[i].to.number
-		from = arcList[i].from.number
-		capacity = arcList[i].capacity
+// discard drops the network and solve state of nw, keeping its settings.
+func (nw *NetworkOf[T]) discard() {
+	*nw = NetworkOf[T]{Ctx: nw.Ctx, Epsilon: nw.Epsilon, Observer: nw.Observer, ProgressInterval: nw.ProgressInterval, MaxNodes: nw.MaxNodes, Logger: nw.Logger,
+		KeepPhaseOne: nw.KeepPhaseOne}
+	nw.defaultEpsilon()
+}
 
-		if !(source == to || sink == from || from == to) {
-			if source == from && to == sink {
-				arcList[i].flow = capacity
-			} else if from == source || to != sink {
-				adjacencyList[from-1].addOutOfTreeNode(arcList[i])
-			} else if to == sink {
-				adjacencyList[to-1].addOutOfTreeNode(arcList[i])
-			} else {
-				adjacencyList[from-1].addOutOfTreeNode(arcList[i])
-			}
-		}
+// maxNodes returns the MaxNodes of nw, or DefaultMaxNodes.
+func (nw *NetworkOf[T]) maxNodes() uint {
+	if nw.MaxNodes == 0 {
+		return DefaultMaxNodes
 	}
+	return nw.MaxNodes
+}
 
-	return nil
+// SimpleInitialization calls SimpleInitialization on the Network read by ReadDimacsFile.
+func SimpleInitialization() error {
+	return getStd().SimpleInitialization()
 }
 
 // SimpleInitialization implements simpleInitialization of C source code.
-func SimpleInitialization() {
+// A Network constructed with NewNetwork is built first; an error is
+// returned if it is not a valid source/sink network.
+func (nw *NetworkOf[T]) SimpleInitialization() error {
 	var i, size uint
-	var tempArc *arc
+	var tempArc *arc[T]
 
-	// debug index out of range
-	fmt.Printf("numArcs = %v\n", numArcs)
-	fmt.Printf("numNodes = %v\n", numNodes)
-	fmt.Printf("size = %v \n", size)
-	fmt.Printf("source = %v \n", source)
+	nw.defaultEpsilon()
+	if !nw.built {
+		if err := nw.build(); err != nil {
+			return err
+		}
+	}
+	nw.initialized = true
+	nw.startPhase(PhaseInitialization)
 
-	size = adjacencyList[source-1].numberOutOfTree
+	size = nw.adjacencyList[nw.source-1].numberOutOfTree
 	for i = 0; i < size; i++ {
-		tempArc = adjacencyList[source-1].outOfTree[i]
+		tempArc = nw.adjacencyList[nw.source-1].outOfTree[i]
 		tempArc.flow = tempArc.capacity
 		tempArc.to.excess += tempArc.capacity
 	}
 
-	size = adjacencyList[sink-1].numberOutOfTree
+	size = nw.adjacencyList[nw.sink-1].numberOutOfTree
 	for i = 0; i < size; i++ {
-		tempArc = adjacencyList[sink-1].outOfTree[i]
+		tempArc = nw.adjacencyList[nw.sink-1].outOfTree[i]
 		tempArc.flow = tempArc.capacity
 		tempArc.from.excess -= tempArc.capacity
 	}
 
-	adjacencyList[source-1].excess = 0
-	adjacencyList[sink-1].excess = 0
+	nw.adjacencyList[nw.source-1].excess = 0
+	nw.adjacencyList[nw.sink-1].excess = 0
 
-	for i = 0; i < numNodes; i++ {
-		if adjacencyList[i].excess > 0 {
-			adjacencyList[i].label = 1
-			labelCount[1]++
-			adjacencyList[i].addToStrongBucket(strongRoots[1])
+	for i = 0; i < nw.numNodes; i++ {
+		if nw.positive(nw.adjacencyList[i].excess) {
+			nw.adjacencyList[i].label = 1
+			nw.labelCount[1]++
+			nw.addToStrongBucket(nw.adjacencyList[i], nw.strongRoots[1])
 		}
 	}
 
-	adjacencyList[source-1].label = numNodes
-	adjacencyList[sink-1].label = 0
-	labelCount[0] = (numNodes - 2) - labelCount[1]
+	nw.adjacencyList[nw.source-1].label = nw.numNodes
+	nw.adjacencyList[nw.sink-1].label = 0
+	nw.labelCount[0] = (nw.numNodes - 2) - nw.labelCount[1]
+	nw.logger().Debug("simple initialization", "nodes", nw.numNodes, "arcs", nw.numArcs,
+		"strongRoots", nw.labelCount[1])
+
+	nw.finishPhase(PhaseInitialization)
+	return nil
 }
 
-// FlowPhaseOne implements pseudoFlowPhaseOne of C source code.
+// FlowPhaseOne calls FlowPhaseOne on the Network read by ReadDimacsFile.
 func FlowPhaseOne() {
-	var strongRoot *node
+	getStd().FlowPhaseOne()
+}
+
+// FlowPhaseOne implements pseudoFlowPhaseOne of C source code.
+func (nw *NetworkOf[T]) FlowPhaseOne() {
+	nw.flowPhaseOne(context.Background())
+}
 
-	if PseudoCtx.LowestLabel {
-		strongRoot = getLowestStrongRoot()
-		for ; strongRoot != nil; strongRoot = getLowestStrongRoot() {
-			strongRoot.processRoot()
+// ctxInterval is the number of strong roots, or of flow paths, processed
+// between checks of the context of SolveContext.
+const ctxInterval = 1024
+
+// flowPhaseOne is FlowPhaseOne checking ctx every ctxInterval roots and
+// reporting to the Observer of nw. When it returns ctx.Err() the normalized
+// tree is left between two roots, from which a later solve continues.
+func (nw *NetworkOf[T]) flowPhaseOne(ctx context.Context) error {
+	var strongRoot *node[T]
+	var count uint
+
+	nw.solved = false
+	nw.startPhase(PhaseOne)
+	if nw.Ctx.LowestLabel {
+		strongRoot = nw.getLowestStrongRoot()
+		for ; strongRoot != nil; strongRoot = nw.getLowestStrongRoot() {
+			nw.processRoot(strongRoot)
+			count++
+			if err := nw.checkpoint(ctx, PhaseOne, count, 0); err != nil {
+				return err
+			}
 		}
 	} else {
-		strongRoot = getHighestStrongRoot()
-		for ; strongRoot != nil; strongRoot = getHighestStrongRoot() {
-			strongRoot.processRoot()
+		strongRoot = nw.getHighestStrongRoot()
+		for ; strongRoot != nil; strongRoot = nw.getHighestStrongRoot() {
+			nw.processRoot(strongRoot)
+			count++
+			if err := nw.checkpoint(ctx, PhaseOne, count, 0); err != nil {
+				return err
+			}
 		}
 	}
+	nw.finishPhase(PhaseOne)
+	return nil
+}
+
+// gap returns the label at or above which nodes are on the source side
+// of the min cut once FlowPhaseOne has run.
+// Setting gap value is taken out of main() in C source code.
+func (nw *NetworkOf[T]) gap() uint {
+	if nw.Ctx.LowestLabel {
+		return nw.lowestStrongLabel
+	}
+	return nw.numNodes
 }
 
 // static void
 // recoverFlow (const uint gap)
 
+// RecoverFlow calls RecoverFlow on the Network read by ReadDimacsFile.
+func RecoverFlow() {
+	getStd().RecoverFlow()
+}
+
 // RecoverFlow implements recoverFlow of C source code.
 // It internalizes setting 'gap' value.
-func RecoverFlow() {
-	// setting gap value is taken out of main() in C source code
-	var gap uint
-	if PseudoCtx.LowestLabel {
-		gap = lowestStrongLabel
-	} else {
-		gap = numNodes
+// The state it overwrites is kept for UpdateCapacity if KeepPhaseOne is set.
+func (nw *NetworkOf[T]) RecoverFlow() {
+	nw.recoverFlow(context.Background())
+}
+
+// recoverFlow is RecoverFlow checking ctx every ctxInterval flow paths and
+// reporting to the Observer of nw. When it returns ctx.Err() the flow is
+// incomplete, but the state of FlowPhaseOne is kept as for UpdateCapacity.
+func (nw *NetworkOf[T]) recoverFlow(ctx context.Context) error {
+	nw.startPhase(PhaseRecoverFlow)
+	if nw.KeepPhaseOne {
+		nw.savePhaseOne()
 	}
+	nw.recovered = true
+	nw.solved = false
+	gap := nw.gap()
 
-	var i, j uint
+	var i, j, paths uint
 	iteration := uint(1)
-	var tempArc *arc
-	var tempNode *node
+	var tempArc *arc[T]
+	var tempNode *node[T]
 
-	for i = 0; i < adjacencyList[sink-1].numberOutOfTree; i++ {
-		tempArc = adjacencyList[sink-1].outOfTree[i]
-		if tempArc.from.excess < uint(0) {
-			if tempArc.from.excess+tempArc.flow < uint(0) {
+	for i = 0; i < nw.adjacencyList[nw.sink-1].numberOutOfTree; i++ {
+		tempArc = nw.adjacencyList[nw.sink-1].outOfTree[i]
+		if nw.negative(tempArc.from.excess) {
+			if nw.negative(tempArc.from.excess + tempArc.flow) {
 				tempArc.from.excess += tempArc.flow
-				tempArc.flow = uint(0)
+				tempArc.flow = 0
 			} else {
 				tempArc.flow = tempArc.from.excess + tempArc.flow
-				tempArc.from.excess = uint(0)
+				tempArc.from.excess = 0
 			}
 		}
 	}
 
-	for i = 0; i < adjacencyList[source-1].numberOutOfTree; i++ {
-		tempArc = adjacencyList[source-1].outOfTree[i]
+	nw.cancelDeficits()
+
+	for i = 0; i < nw.adjacencyList[nw.source-1].numberOutOfTree; i++ {
+		tempArc = nw.adjacencyList[nw.source-1].outOfTree[i]
 		tempArc.to.addOutOfTreeNode(tempArc)
 	}
 
-	adjacencyList[source-1].excess = uint(0)
-	adjacencyList[sink-1].excess = uint(0)
+	nw.adjacencyList[nw.source-1].excess = 0
+	nw.adjacencyList[nw.sink-1].excess = 0
 
-	for i = 0; i < numNodes; i++ {
-		tempNode = adjacencyList[i]
-		if i == source-1 || i == sink-1 {
+	for i = 0; i < nw.numNodes; i++ {
+		tempNode = nw.adjacencyList[i]
+		if i == nw.source-1 || i == nw.sink-1 {
 			continue
 		}
 
 		if tempNode.label >= gap {
 			tempNode.nextArc = 0
-			if tempNode.parent != nil && tempNode.arcToParent.flow != 0 {
+			if tempNode.parent != nil && !nw.zero(tempNode.arcToParent.flow) {
 				tempNode.arcToParent.to.addOutOfTreeNode(tempNode.arcToParent)
 			}
 
 			for j = 0; j < tempNode.numberOutOfTree; j++ {
-				if tempNode.outOfTree[j].flow != 0 {
+				if nw.zero(tempNode.outOfTree[j].flow) {
 					tempNode.numberOutOfTree--
 					tempNode.outOfTree[j] = tempNode.outOfTree[tempNode.numberOutOfTree]
 					j--
@@ -953,13 +1115,23 @@ func RecoverFlow() {
 		}
 	}
 
-	for i = 0; i < numNodes; i++ {
-		tempNode = adjacencyList[i]
-		for tempNode.excess > 0 {
+	for i = 0; i < nw.numNodes; i++ {
+		tempNode = nw.adjacencyList[i]
+		for nw.positive(tempNode.excess) {
 			iteration++
-			tempNode.decompose(source, &iteration)
+			if !nw.decompose(tempNode, &iteration) {
+				nw.logger().Debug("excess left on node", "node", tempNode.number, "excess", tempNode.excess)
+				break
+			}
+			paths++
+			if err := nw.checkpoint(ctx, PhaseRecoverFlow, paths, i); err != nil {
+				return err
+			}
 		}
 	}
+	nw.solved = true
+	nw.finishPhase(PhaseRecoverFlow)
+	return nil
 }
 
 // Result returns scan of arc/node results in Dimac syntax.
@@ -987,108 +1159,201 @@ func RecoverFlow() {
 //	f 1 3 10
 //	...
 func Result(header string) []string {
-	// header and runtime config info
-	ret := []string{
-		"c " + header,
-		"c ",
-		"c Dimacs-format maximum flow result file",
-		"c generated by pseudo.go",
-		"c ",
-		"c Optimal flow using  Hochbaum's PseudoFlow algorithm",
-		"c ",
-		"c Runtime Configuration -"}
-
-	if PseudoCtx.LowestLabel {
-		ret = append(ret, "c Lowest label pseudoflow algorithm")
-	} else {
-		ret = append(ret, "c Highest label pseudoflow algorithm")
-	}
-	if PseudoCtx.FifoBucket {
-		ret = append(ret, "c Using FIFO buckets")
-	} else {
-		ret = append(ret, "c Using LIFO buckets")
-	}
-	ret = append(ret, "c ")
-
-	// add Solution
-	ret = append(ret, checkOptimality()...)
-
-	// add flows
-	ret = append(ret, "c ", "c SRC DST FLOW")
-	ret = append(ret, displayFlow()...)
+	return getStd().Result(header)
+}
 
-	return ret
+// Result returns scan of arc/node results of nw in Dimac syntax.
+// See the package-level Result for an example. It returns nil if nw has
+// no recovered flow, as for WriteResult.
+func (nw *NetworkOf[T]) Result(header string) []string {
+	if !nw.solved {
+		return nil
+	}
+	return nw.solution().Dimacs(header)
 }
 
 // timing info in case someone wants it as in C source main()
-var timer = struct {
+type timings struct {
 	start, readfile, initialize, flow, recflow time.Time
-}{}
+	read                                       bool // start and readfile time a read not yet solved
+}
+
+// startSolve starts the Statistics and timings of a solve; those of the
+// first solve after ReadDimacs or ReadBinary include the read.
+func (nw *NetworkOf[T]) startSolve() {
+	nw.stats = Statistics{}
+	if !nw.timer.read {
+		nw.timer.start = time.Now()
+		nw.timer.readfile = nw.timer.start
+	}
+	nw.timer.read = false
+}
 
 // TimerJSON return timings of the 4 processing steps of Run -
 // ReadDimacsFile, SimpleInitialization, FlowPhaseOne, and RecoverFlow.
 // Note: the file initialization and result marshaling times are not
 // included in result.
 func TimerJSON() string {
-	type times struct {
-		ReadDimacsFile, SimpleInitialization, FlowPhaseOne, RecoverFlow, Total time.Duration
+	return getStd().TimerJSON()
+}
+
+// TimerJSON returns the timings of nw as for the package-level TimerJSON.
+func (nw *NetworkOf[T]) TimerJSON() string {
+	j, _ := json.Marshal(nw.timings())
+	return string(j)
+}
+
+func (nw *NetworkOf[T]) timings() Timings {
+	return Timings{
+		nw.timer.readfile.Sub(nw.timer.start),
+		nw.timer.initialize.Sub(nw.timer.readfile),
+		nw.timer.flow.Sub(nw.timer.initialize),
+		nw.timer.recflow.Sub(nw.timer.flow),
+		nw.timer.recflow.Sub(nw.timer.start),
 	}
-	data := times{
-		timer.readfile.Sub(timer.start),
-		timer.initialize.Sub(timer.readfile),
-		timer.flow.Sub(timer.initialize),
-		timer.recflow.Sub(timer.flow),
-		timer.recflow.Sub(timer.start),
+}
+
+// Solve runs SimpleInitialization, FlowPhaseOne and RecoverFlow on a Network
+// that has been read or constructed but not yet initialized, and returns
+// the Solution.
+func (nw *NetworkOf[T]) Solve() (*SolutionOf[T], error) {
+	return nw.SolveContext(context.Background())
+}
+
+// SolveContext is Solve that stops once ctx is done, checking it between
+// the processing steps of C source main() and periodically within
+// FlowPhaseOne and RecoverFlow. It then returns ctx.Err() with a partial
+// Solution that holds only the Stats, Timings and Ctx of the solve so far;
+// the step that was stopped ends at the time it stopped.
+// A stopped solve may be resumed by solving nw again. One stopped within
+// FlowPhaseOne continues it from where it was stopped; one stopped within
+// RecoverFlow continues from the end of FlowPhaseOne if KeepPhaseOne is set,
+// and starts over from SimpleInitialization if not. Either way it is not
+// counted in Statistics.NumWarmStarts.
+func (nw *NetworkOf[T]) SolveContext(ctx context.Context) (*SolutionOf[T], error) {
+	nw.startSolve()
+	if err := ctx.Err(); err != nil {
+		return nw.stopped(&nw.timer.initialize), err
 	}
-	j, _ := json.Marshal(data)
-	return string(j)
+	if err := nw.startPhaseOne(); err != nil {
+		return nil, err
+	}
+	nw.timer.initialize = time.Now()
+	if err := nw.runPhaseOne(ctx); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nw.stopped(&nw.timer.flow), ctxErr
+		}
+		return nil, err
+	}
+	nw.timer.flow = time.Now()
+	if err := ctx.Err(); err != nil {
+		return nw.stopped(&nw.timer.recflow), err
+	}
+	if err := nw.recoverFlow(ctx); err != nil {
+		return nw.stopped(&nw.timer.recflow), err
+	}
+	nw.timer.recflow = time.Now()
+
+	return nw.solution(), nil
+}
+
+// stopped ends the timings of a solve stopped by its context at the step
+// whose end is 'end', and returns the partial Solution of SolveContext.
+func (nw *NetworkOf[T]) stopped(end *time.Time) *SolutionOf[T] {
+	now := time.Now()
+	steps := []*time.Time{&nw.timer.initialize, &nw.timer.flow, &nw.timer.recflow}
+	for i := len(steps) - 1; i >= 0; i-- {
+		*steps[i] = now
+		if steps[i] == end {
+			break
+		}
+	}
+	return &SolutionOf[T]{Stats: nw.stats, Timings: nw.timings(), Ctx: nw.Ctx}
 }
 
 // Run takes an input file and returns Result having
 // called all public functions in sequence. If input == "stdin"
-// then os.Stdin is read.
+// then os.Stdin is read. Input compressed with gzip or bzip2
+// is decompressed. Each call solves on its own Network, so
+// Run can be called concurrently; StatsJSON and TimerJSON report
+// on the most recently completed Run.
 func Run(input string) ([]string, error) {
-	var fh *os.File
-	var err error
+	sol, err := Solve(input)
+	if err != nil {
+		return nil, err
+	}
+	return sol.Dimacs("Data: " + input), nil
+}
+
+// Solve is Run returning the Solution rather than its Dimacs text.
+func Solve(input string) (*Solution, error) {
 	if strings.ToLower(input) == "stdin" {
-		fh = os.Stdin
-	} else {
-		fh, err = os.Open(input)
-		if err != nil {
-			return nil, err
-		}
+		return solve(context.Background(), os.Stdin)
+	}
+	fh, err := os.Open(input)
+	if err != nil {
+		return nil, err
 	}
 	defer fh.Close()
 
-	// implement C source main()
-	timer.start = time.Now()
-	if err = ReadDimacsFile(fh); err != nil {
+	return solve(context.Background(), fh)
+}
+
+// RunReader is Run for DIMACS data read from r.
+func RunReader(r io.Reader) ([]string, error) {
+	return RunReaderContext(context.Background(), r)
+}
+
+// RunReaderContext is RunReader that stops reading or solving once
+// ctx is done, returning ctx.Err().
+func RunReaderContext(ctx context.Context, r io.Reader) ([]string, error) {
+	sol, err := solve(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return sol.Dimacs("Data: io.Reader"), nil
+}
+
+// solve reads r into a new Network and solves it.
+func solve(ctx context.Context, r io.Reader) (*Solution, error) {
+	nw := &Network{Ctx: PseudoCtx, Logger: PseudoLogger}
+	if err := nw.ReadDimacs(ctxReader{ctx, r}); err != nil {
+		return nil, err
+	}
+	sol, err := nw.SolveContext(ctx)
+	if err != nil {
 		return nil, err
 	}
-	timer.readfile = time.Now()
-	SimpleInitialization()
-	timer.initialize = time.Now()
-	FlowPhaseOne()
-	timer.flow = time.Now()
-	RecoverFlow()
-	timer.recflow = time.Now()
-	ret := Result("Data: " + input)
+	setStd(nw)
 
-	return ret, nil
+	return sol, nil
+}
+
+// ctxReader fails reads once ctx is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
 }
 
 // ======================== quicksort implementation
 
 // static void
 // quickSort (Arc **arr, const uint first, const uint last)
-// CLB: **Arc value is []*arc; slices manipulate the backing array
-func quickSort(arr []*arc, first, last uint) {
+// CLB: **Arc value is []*arc[T]; slices manipulate the backing array
+func quickSort[T Capacity](arr []*arc[T], first, last uint) {
 	left, right := first, last
-	var swap *arc
+	var swap *arc[T]
 
 	// Bubble sort if 5 elements or less
 	if (right - left) <= 5 {
-		for i := right; i > left; i++ {
+		for i := right; i > left; i-- {
 			swap = nil
 			for j := left; j < i; j++ {
 				if arr[j].flow < arr[j+1].flow {
@@ -1097,7 +1362,7 @@ func quickSort(arr []*arc, first, last uint) {
 					arr[j+1] = swap
 				}
 			}
-			if swap != nil {
+			if swap == nil {
 				return
 			}
 		}
@@ -4,7 +4,26 @@
 package pseudo_test
 
 import (
-	"github.com/clbanning/pseudo"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/qarth/pseudo"
 )
 
 func init() {
@@ -16,3 +35,1916 @@ func main() {
 	pseudo.Run("textdata.txt")
 
 }
+
+const maxfFile = "examples/dimacsMaxf.txt"
+
+// ctxs are all combinations of the Context switches that change the solve.
+var ctxs = []pseudo.Context{
+	{},
+	{LowestLabel: true},
+	{FifoBucket: true},
+	{LowestLabel: true, FifoBucket: true},
+}
+
+// phaseOne runs the min cut phase on its own Network and returns the stats.
+func phaseOne(t *testing.T, ctx pseudo.Context) string {
+	fh, err := os.Open(maxfFile)
+	if err != nil {
+		t.Error(err)
+		return ""
+	}
+	defer fh.Close()
+
+	nw := &pseudo.Network{Ctx: ctx}
+	if err := nw.ReadDimacsFile(fh); err != nil {
+		t.Error(err)
+		return ""
+	}
+	if err := nw.SimpleInitialization(); err != nil {
+		t.Error(err)
+		return ""
+	}
+	nw.FlowPhaseOne()
+	return nw.StatsJSON()
+}
+
+func TestNetworkConcurrent(t *testing.T) {
+	want := make([]string, len(ctxs))
+	for i, ctx := range ctxs {
+		want[i] = phaseOne(t, ctx)
+	}
+
+	var wg sync.WaitGroup
+	got := make([]string, 4*len(ctxs))
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i] = phaseOne(t, ctxs[i%len(ctxs)])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, g := range got {
+		if w := want[i%len(ctxs)]; g != w {
+			t.Errorf("solve %d: stats %s, want %s", i, g, w)
+		}
+	}
+}
+
+func TestNewNetwork(t *testing.T) {
+	ctx := pseudo.Context{LowestLabel: true}
+	want := phaseOne(t, ctx)
+
+	// examples/dimacsMaxf.txt
+	nw := pseudo.NewNetwork(6)
+	nw.Ctx = ctx
+	nw.SetSource(1)
+	nw.SetSink(6)
+	arcs := [][3]uint{{1, 2, 5}, {1, 3, 15}, {2, 4, 5}, {2, 5, 5}, {3, 4, 5}, {3, 5, 5}, {4, 6, 15}, {5, 6, 5}}
+	for i, a := range arcs {
+		if id := nw.AddArc(a[0], a[1], int64(a[2])); id != pseudo.ArcID(i) {
+			t.Fatalf("AddArc: got id %d, want %d", id, i)
+		}
+	}
+	if err := nw.SimpleInitialization(); err != nil {
+		t.Fatal(err)
+	}
+	nw.FlowPhaseOne()
+	if got := nw.StatsJSON(); got != want {
+		t.Errorf("stats %s, want %s", got, want)
+	}
+}
+
+func TestNewNetworkOverflow(t *testing.T) {
+	nw := pseudo.NewNetwork(3)
+	nw.SetSource(1)
+	nw.SetSink(3)
+	nw.AddArc(1, 2, math.MaxInt64)
+	nw.AddArc(2, 3, 1)
+	if err := nw.SimpleInitialization(); err == nil {
+		t.Error("no error for total capacity overflow")
+	}
+
+	nw = pseudo.NewNetwork(2)
+	nw.SetSource(1)
+	nw.SetSink(2)
+	nw.AddArc(1, 2, -1)
+	if err := nw.SimpleInitialization(); err == nil {
+		t.Error("no error for negative capacity")
+	}
+}
+
+func TestNewNetworkNoSink(t *testing.T) {
+	nw := pseudo.NewNetwork(2)
+	nw.SetSource(1)
+	nw.AddArc(1, 2, 1)
+	if err := nw.SimpleInitialization(); err == nil {
+		t.Error("no error for network without a sink")
+	}
+}
+
+func TestReadDimacs(t *testing.T) {
+	ctx := pseudo.Context{LowestLabel: true}
+	want := phaseOne(t, ctx)
+
+	data, err := os.ReadFile(maxfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nw := &pseudo.Network{Ctx: ctx}
+	if err := nw.ReadDimacs(bytes.NewBuffer(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := nw.SimpleInitialization(); err != nil {
+		t.Fatal(err)
+	}
+	nw.FlowPhaseOne()
+	if got := nw.StatsJSON(); got != want {
+		t.Errorf("stats %s, want %s", got, want)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, tc := range []struct {
+		data         string
+		line, column uint
+		msg          string
+	}{
+		{"", 0, 0, "no problem line"},
+		{"c only a comment\n\n", 2, 0, "no problem line"},
+		{"a 1 2 3\n", 1, 1, `"a" line before problem line`},
+		{"x\n", 1, 1, `"x" line before problem line`},
+		{"pmax 2 0\n", 1, 1, `unknown line type "pmax"`},
+		{"p min 2 0\n", 1, 3, `problem type "min" is not max`},
+		{"p max 1 0\n", 1, 7, "1 nodes, a source and a sink need 2"},
+		{"p max -2 0\n", 1, 7, `number of nodes "-2": invalid syntax`},
+		{"p max 100000000 0\n", 1, 7, "100000000 nodes, more than the limit of 67108864"},
+		{"p max 2\n", 1, 8, "missing number of arcs"},
+		{"p max 2 0 x\n", 1, 11, `unexpected "x"`},
+		{"p max 2 0\np max 2 0\n", 2, 1, "second problem line"},
+		{"p max 3 1\nn 4 s\n", 2, 3, "node 4 out of range 1..3"},
+		{"p max 3 1\nn 1 x\n", 2, 5, `node designation "x" is not s or t`},
+		{"p max 3 1\nn 1 s\nn 2 s\n", 3, 5, "second source, the first is on line 2"},
+		{"p max 3 1\nn 1 t\nn\t2  t\n", 3, 6, "second sink, the first is on line 2"},
+		{"p max 3 1\na 1 0 5\n", 2, 5, "to node 0 out of range 1..3"},
+		{"p max 3 1\na 1 2\n", 2, 6, "missing capacity"},
+		{"p max 3 1\na 1 2 -3\n", 2, 7, "capacity -3 is not non-negative"},
+		{"p max 3 1\na 1 2 1.5\n", 2, 7, `capacity "1.5": invalid syntax`},
+		{"p max 3 2\na 1 2 9223372036854775807\na 2 3 1\n", 3, 7, "total arc capacity overflows int64"},
+		{"p max 3 1\na 1 2 1\na 2 3 1\n", 3, 1, "more arc lines than the 1 of the problem line"},
+		{"p max 3 2\nn 1 s\nn 3 t\na 1 2 1\n", 4, 0, "1 arc lines, the problem line has 2"},
+		{"p max 3 0\nn 3 t\n", 2, 0, "no source node"},
+		{"p max 3 0\nn 1 s\n", 2, 0, "no sink node"},
+		{"p max 3 0\nn 1 s\nn 1 t\n", 3, 0, "source and sink are both node 1, on lines 2 and 3"},
+	} {
+		nw := new(pseudo.Network)
+		err := nw.ReadDimacs(strings.NewReader(tc.data))
+		var perr *pseudo.ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: error %v, want a *ParseError", tc.data, err)
+			continue
+		}
+		if perr.Line != tc.line || perr.Column != tc.column || perr.Msg != tc.msg {
+			t.Errorf("%q: error %+v, want line %d, column %d: %s", tc.data, *perr, tc.line, tc.column, tc.msg)
+		}
+	}
+
+	// CRLF line ends, blank lines and no final line end are accepted.
+	data := "c CRLF\r\np max 6 8\r\n\r\nn 1 s\r\nn 6 t\r\na 1 2 5\r\na 1 3 15\r\na 2 4 5\r\na 2 5 5\r\n" +
+		"a 3 4 5\r\na 3 5 5\r\na 4 6 15\r\na 5 6 5"
+	nw := new(pseudo.Network)
+	if err := nw.ReadDimacs(strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	sol, err := nw.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sol.Flow != 15 {
+		t.Errorf("flow %d, want 15", sol.Flow)
+	}
+}
+
+func FuzzReadDimacs(f *testing.F) {
+	data, err := os.ReadFile(maxfFile)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+	f.Add(genDimacs(10, 30, 1))
+	f.Add([]byte("p max 3 2\nn 1 s\nn 3 t\na 1 2 4\na 2 3 4\n"))
+	f.Add([]byte("p max 2 1\nn 2 s\nn 1 t\na 1 1 5"))
+	f.Add([]byte("p max 4 3\r\nn 1 s\r\nn 4 t\r\n\r\na 1 2 9223372036854775807\r\na 2 4 0\r\na 3 4 1\r\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		nw := &pseudo.Network{MaxNodes: 1 << 16}
+		err := nw.ReadDimacs(bytes.NewReader(data))
+		if err != nil {
+			var perr *pseudo.ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("error %v is not a *ParseError", err)
+			}
+			return
+		}
+		sol, err := nw.Solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sol.Feasible || !sol.Optimal {
+			t.Errorf("feasible %v, optimal %v: %v", sol.Feasible, sol.Optimal, sol.Violations)
+		}
+	})
+}
+
+func TestCompressedInput(t *testing.T) {
+	data, err := os.ReadFile(maxfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	gzFile := filepath.Join(t.TempDir(), "dimacsMaxf.txt.gz")
+	if err := os.WriteFile(gzFile, gz.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := pseudo.Solve(maxfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range []string{gzFile, maxfFile + ".bz2"} {
+		sol, err := pseudo.Solve(input)
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+		if sol.Flow != want.Flow || len(sol.Arcs) != len(want.Arcs) {
+			t.Errorf("%s: flow %d on %d arcs, want %d on %d", input, sol.Flow, len(sol.Arcs), want.Flow, len(want.Arcs))
+		}
+	}
+
+	nw := new(pseudo.Network)
+	if err := nw.ReadDimacs(bytes.NewReader(gz.Bytes()[:gz.Len()/2])); err == nil {
+		t.Error("no error for truncated gzip input")
+	}
+	if err := nw.ReadDimacs(strings.NewReader("\x1f\x8b not gzip")); err == nil {
+		t.Error("no error for bad gzip header")
+	}
+}
+
+func TestBinary(t *testing.T) {
+	data := genDimacs(2000, 10000, 1)
+	nw := new(pseudo.Network)
+	if err := nw.ReadDimacs(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	want, err := nw.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, checksum := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := nw.WriteBinary(&buf, checksum); err != nil {
+			t.Fatal(err)
+		}
+		var conv bytes.Buffer
+		if err := pseudo.ConvertDimacs(&conv, bytes.NewReader(data), checksum); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(conv.Bytes(), buf.Bytes()) {
+			t.Errorf("checksum %v: ConvertDimacs differs from WriteBinary", checksum)
+		}
+
+		bin := new(pseudo.Network)
+		if err := bin.ReadBinary(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+		sol, err := bin.Solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSolution(t, sol, want.Flow)
+		for i, a := range sol.Arcs {
+			if w := want.Arcs[i]; a.From != w.From || a.To != w.To || a.Capacity != w.Capacity {
+				t.Fatalf("checksum %v: arc %d is %+v, want %+v", checksum, i, a, w)
+			}
+		}
+
+		if err := bin.ReadBinary(bytes.NewReader(buf.Bytes()[:buf.Len()-1])); err != io.ErrUnexpectedEOF {
+			t.Errorf("checksum %v: truncated input error %v", checksum, err)
+		}
+		if err := new(pseudo.NetworkOf[float64]).ReadBinary(bytes.NewReader(buf.Bytes())); err == nil {
+			t.Errorf("checksum %v: no error reading int64 capacities as float64", checksum)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := nw.WriteBinary(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	b[len(b)/2]++
+	if err := new(pseudo.Network).ReadBinary(bytes.NewReader(b)); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("corrupted input error %v", err)
+	}
+	if err := new(pseudo.Network).ReadBinary(bytes.NewReader(data)); err == nil {
+		t.Error("no error reading DIMACS text as binary")
+	}
+
+	// corrupted node counts, caught by the checksum or the node limit
+	for numNodes, msg := range map[uint32]string{1 << 20: "checksum", math.MaxUint32: "limit"} {
+		buf.Reset()
+		if err := nw.WriteBinary(&buf, true); err != nil {
+			t.Fatal(err)
+		}
+		b := buf.Bytes()
+		binary.LittleEndian.PutUint32(b[8:], numNodes)
+		err := new(pseudo.Network).ReadBinary(bytes.NewReader(b))
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%d nodes: corrupted header error %v", numNodes, err)
+		}
+	}
+
+	fnw := pseudo.NewNetworkOf[float64](3)
+	fnw.AddArc(1, 2, 0.5)
+	fnw.AddArc(2, 3, 1.25)
+	fnw.SetSource(1)
+	fnw.SetSink(3)
+	buf.Reset()
+	if err := fnw.WriteBinary(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	fbin := new(pseudo.NetworkOf[float64])
+	if err := fbin.ReadBinary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if sol, err := fbin.Solve(); err != nil || sol.Flow != 0.5 {
+		t.Errorf("float64 network: flow %v, error %v", sol.Flow, err)
+	}
+}
+
+func TestWriteDimacs(t *testing.T) {
+	data := genDimacs(2000, 10000, 1)
+	nw := new(pseudo.Network)
+	if err := nw.ReadDimacs(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	sol, err := nw.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the input without its comment line
+	var buf bytes.Buffer
+	if err := nw.WriteDimacs(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := data[bytes.IndexByte(data, '\n')+1:]; !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("WriteDimacs differs from its input")
+	}
+
+	buf.Reset()
+	if err := sol.WriteDimacs(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if want := fmt.Sprintf("s %d", sol.Flow); lines[0] != want {
+		t.Errorf("solution line %q, want %q", lines[0], want)
+	}
+	flows := make(map[pseudo.ArcID]pseudo.ArcFlow)
+	for _, a := range sol.Arcs {
+		flows[a.ID] = a
+	}
+	if len(lines) != len(sol.Arcs)+1 {
+		t.Fatalf("%d lines for %d arcs", len(lines), len(sol.Arcs))
+	}
+	for i, line := range lines[1:] {
+		a := flows[pseudo.ArcID(i)]
+		if want := fmt.Sprintf("f %d %d %d", a.From, a.To, a.Flow); line != want {
+			t.Fatalf("flow line %d is %q, want %q", i, line, want)
+		}
+	}
+
+	fnw := pseudo.NewNetworkOf[float64](3)
+	fnw.AddArc(2, 3, 0.1)
+	fnw.AddArc(1, 2, 1e-300)
+	fnw.AddArc(1, 3, 1.0/3)
+	fnw.SetSource(1)
+	fnw.SetSink(3)
+	buf.Reset()
+	if err := fnw.WriteDimacs(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "p max 3 3\nn 1 s\nn 3 t\na 2 3 0.1\na 1 2 1e-300\na 1 3 0.3333333333333333\n"; got != want {
+		t.Errorf("float64 network %q, want %q", got, want)
+	}
+	if err := pseudo.NewNetwork(3).WriteDimacs(&buf); err == nil {
+		t.Error("no error writing a network without a source")
+	}
+}
+
+func TestArcOrder(t *testing.T) {
+	data, err := os.ReadFile(maxfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var arcs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "a ") {
+			arcs = append(arcs, strings.Join(strings.Fields(line)[1:3], " "))
+		}
+	}
+
+	lines, err := pseudo.Run(maxfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var flows []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "f ") {
+			flows = append(flows, strings.Join(strings.Fields(line)[1:3], " "))
+		}
+	}
+	if strings.Join(flows, ", ") != strings.Join(arcs, ", ") {
+		t.Errorf("flow lines for arcs %q, want the input order %q", flows, arcs)
+	}
+
+	sol, err := pseudo.Solve(maxfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, a := range sol.Arcs {
+		if got, ok := sol.Arc(pseudo.ArcID(i)); !ok || got != a || a.ID != pseudo.ArcID(i) {
+			t.Errorf("Arc(%d) = %+v, %v, want %+v", i, got, ok, a)
+		}
+	}
+	if a, ok := sol.Arc(pseudo.ArcID(len(arcs))); ok {
+		t.Errorf("Arc(%d) = %+v past the last arc", len(arcs), a)
+	}
+	// arcs 1->3 and 4->6 carry 10 units of the flow of 15
+	for _, id := range []pseudo.ArcID{1, 6} {
+		if a, _ := sol.Arc(id); a.Flow != 10 {
+			t.Errorf("arc %d (%d, %d): flow %d, want 10", id, a.From, a.To, a.Flow)
+		}
+	}
+}
+
+func TestWriteResult(t *testing.T) {
+	data := genDimacs(2000, 10000, 1)
+	for _, ctx := range []pseudo.Context{{}, {LowestLabel: true, DisplayCut: true}} {
+		nw := &pseudo.Network{Ctx: ctx}
+		if err := nw.ReadDimacs(bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		sol, err := nw.Solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := strings.Join(nw.Result("random"), "\n") + "\n"
+		if dimacs := strings.Join(sol.Dimacs("random"), "\n") + "\n"; dimacs != want {
+			t.Errorf("%+v: Solution.Dimacs differs from Result", ctx)
+		}
+
+		var buf bytes.Buffer
+		if err := nw.WriteResult(&buf, pseudo.ResultOptions{Header: "random"}); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want {
+			t.Errorf("%+v: Network.WriteResult differs from Result:\n%s", ctx, buf.String())
+		}
+		buf.Reset()
+		if err := sol.WriteResult(&buf, pseudo.ResultOptions{Header: "random"}); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want {
+			t.Errorf("%+v: Solution.WriteResult differs from Result", ctx)
+		}
+
+		buf.Reset()
+		if err := nw.WriteResult(&buf, pseudo.ResultOptions{OmitZeroFlow: true, FlowLine: 'a'}); err != nil {
+			t.Fatal(err)
+		}
+		var flows []string
+		for _, a := range sol.Arcs {
+			if a.Flow != 0 {
+				flows = append(flows, fmt.Sprintf("a %d %d %d", a.From, a.To, a.Flow))
+			}
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if got := lines[len(lines)-len(flows):]; strings.Join(got, "\n") != strings.Join(flows, "\n") ||
+			lines[len(lines)-len(flows)-1] != "c SRC DST FLOW" {
+			t.Errorf("%+v: flow lines without zero flows differ", ctx)
+		}
+	}
+
+	if err := new(pseudo.Solution).WriteResult(io.Discard, pseudo.ResultOptions{FlowLine: 'x'}); err == nil {
+		t.Error("no error for flow line type x")
+	}
+
+	// not solved, solved for the cut only, and changed since the solve
+	cut := &pseudo.Network{}
+	if err := cut.ReadDimacs(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cut.SolveMinCut(); err != nil {
+		t.Fatal(err)
+	}
+	changed := &pseudo.Network{}
+	if err := changed.ReadDimacs(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := changed.Solve(); err != nil {
+		t.Fatal(err)
+	}
+	if err := changed.UpdateCapacity(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, nw := range []*pseudo.Network{new(pseudo.Network), pseudo.NewNetwork(2), cut, changed} {
+		if err := nw.WriteResult(io.Discard, pseudo.ResultOptions{}); err == nil {
+			t.Error("no error for a network not solved")
+		}
+		if res := nw.Result(""); res != nil {
+			t.Errorf("result %q of a network not solved", res)
+		}
+	}
+
+	// a float flow within Epsilon of 0 is left out
+	fnw := pseudo.NewNetworkOf[float64](3)
+	fnw.SetSource(1)
+	fnw.SetSink(3)
+	fnw.AddArc(1, 2, 1)
+	fnw.AddArc(2, 3, 1e-12)
+	fnw.AddArc(1, 3, 1)
+	fsol, err := fnw.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []func(io.Writer, pseudo.ResultOptions) error{fnw.WriteResult, fsol.WriteResult} {
+		var buf bytes.Buffer
+		if err := w(&buf, pseudo.ResultOptions{Format: pseudo.FormatCSV, OmitZeroFlow: true}); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := buf.String(), "id,from,to,flow,capacity\n2,1,3,1,1\n"; got != want {
+			t.Errorf("flows within Epsilon of 0 written:\n%s", got)
+		}
+	}
+}
+
+func TestWriteResultFormats(t *testing.T) {
+	for _, name := range []string{"dimacs", "JSON", "csv"} {
+		f, err := pseudo.ParseResultFormat(name)
+		if err != nil || f.String() != strings.ToLower(name) {
+			t.Errorf("ParseResultFormat(%q) = %v, %v", name, f, err)
+		}
+	}
+	if _, err := pseudo.ParseResultFormat("xml"); err == nil {
+		t.Error("no error for result format xml")
+	}
+
+	nw := &pseudo.Network{Ctx: pseudo.Context{LowestLabel: true}}
+	if err := nw.ReadDimacs(bytes.NewReader(genDimacs(200, 1000, 1))); err != nil {
+		t.Fatal(err)
+	}
+	sol, err := nw.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := nw.WriteResult(&buf, pseudo.ResultOptions{Format: pseudo.FormatJSON}); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Flow     int64
+		Feasible bool
+		Optimal  bool
+		Config   pseudo.Context
+		Stats    pseudo.Statistics
+		Timings  pseudo.Timings
+		Cut      struct {
+			SourceSet []uint
+			Capacity  int64
+		}
+		Arcs []pseudo.ArcFlow
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("%v: %s", err, buf.Bytes())
+	}
+	if doc.Flow != sol.Flow || !doc.Feasible || !doc.Optimal || doc.Config != sol.Ctx || doc.Stats != sol.Stats ||
+		doc.Timings != sol.Timings {
+		t.Errorf("JSON result %+v, want %+v", doc, sol)
+	}
+	if doc.Cut.Capacity != sol.Cut.Capacity || fmt.Sprint(doc.Cut.SourceSet) != fmt.Sprint(sol.Cut.SourceSet) {
+		t.Errorf("JSON cut %+v, want %+v", doc.Cut, sol.Cut)
+	}
+	if fmt.Sprint(doc.Arcs) != fmt.Sprint(sol.Arcs) {
+		t.Errorf("JSON arcs %v, want %v", doc.Arcs, sol.Arcs)
+	}
+
+	buf.Reset()
+	if err := sol.WriteResult(&buf, pseudo.ResultOptions{Format: pseudo.FormatCSV, OmitZeroFlow: true}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(records[0], ",") != "id,from,to,flow,capacity" {
+		t.Errorf("CSV header %q", records[0])
+	}
+	records = records[1:]
+	for _, a := range sol.Arcs {
+		if a.Flow == 0 {
+			continue
+		}
+		if len(records) == 0 {
+			t.Fatalf("no CSV record for arc %+v", a)
+		}
+		if got, want := strings.Join(records[0], ","), fmt.Sprintf("%d,%d,%d,%d,%d", a.ID, a.From, a.To, a.Flow, a.Capacity); got != want {
+			t.Errorf("CSV record %q, want %q", got, want)
+		}
+		records = records[1:]
+	}
+	if len(records) != 0 {
+		t.Errorf("%d CSV records too many", len(records))
+	}
+}
+
+func BenchmarkReadBinary(b *testing.B) {
+	nw := new(pseudo.Network)
+	if err := nw.ReadDimacs(bytes.NewReader(genDimacs(100000, 1000000, 1))); err != nil {
+		b.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := nw.WriteBinary(&buf, true); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(buf.Len()))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := nw.ReadBinary(bytes.NewReader(buf.Bytes())); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRunReaderContextCanceled(t *testing.T) {
+	fh, err := os.Open(maxfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pseudo.RunReaderContext(ctx, fh); err != context.Canceled {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
+
+// stopAfter is a context that is canceled from its nth call of Err.
+type stopAfter struct {
+	context.Context
+	n int
+}
+
+func (c *stopAfter) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestSolveContext(t *testing.T) {
+	nw := &pseudo.Network{}
+	if err := nw.ReadDimacs(bytes.NewReader(genDimacs(20000, 100000, 1))); err != nil {
+		t.Fatal(err)
+	}
+	sol, err := nw.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	arcs, want := sol.Arcs, sol.Flow
+	network := func(ctx pseudo.Context) *pseudo.Network {
+		return freshNetwork(ctx, 20000, 20000, arcs)
+	}
+
+	for _, ctx := range ctxs {
+		// stopped within FlowPhaseOne and before starting
+		for _, n := range []int{2, 4, 0} {
+			for _, keep := range []bool{false, true} {
+				nw := network(ctx)
+				nw.KeepPhaseOne = keep
+				sol, err := nw.SolveContext(&stopAfter{context.Background(), n})
+				if err != context.Canceled {
+					t.Fatalf("%+v: stop after %d: got error %v, want %v", ctx, n, err, context.Canceled)
+				}
+				if sol == nil || sol.Arcs != nil || sol.Timings.Total < 0 {
+					t.Fatalf("%+v: stop after %d: partial solution %+v", ctx, n, sol)
+				}
+				if n > 0 && sol.Stats.NumPushes == 0 {
+					t.Errorf("%+v: stop after %d: no statistics", ctx, n)
+				}
+
+				// solving again resumes, which is no warm start
+				if sol, err = nw.Solve(); err != nil {
+					t.Fatal(err)
+				}
+				checkSolution(t, sol, want)
+				if sol.Stats.NumWarmStarts != 0 {
+					t.Errorf("%+v keep %v: stop after %d: resume counted as a warm start", ctx, keep, n)
+				}
+			}
+		}
+
+		// Stopped within RecoverFlow, the kept phase one is not run again;
+		// each pair of nodes returns its excess to the source on a path.
+		var fan []pseudo.ArcFlow
+		for n := uint(2); n < 4000; n += 2 {
+			fan = append(fan, pseudo.ArcFlow{From: 1, To: n, Capacity: 10}, pseudo.ArcFlow{From: n, To: n + 1, Capacity: 10},
+				pseudo.ArcFlow{From: n + 1, To: 4001, Capacity: 1})
+		}
+		for _, keep := range []bool{false, true} {
+			nw := freshNetwork(ctx, 4001, 4001, fan)
+			nw.KeepPhaseOne = keep
+			stop, cancel := context.WithCancel(context.Background())
+			nw.Observer = &cancelIn{phase: pseudo.PhaseRecoverFlow, cancel: cancel}
+			if _, err := nw.SolveContext(stop); err != context.Canceled {
+				t.Fatalf("%+v: stop in RecoverFlow: got error %v, want %v", ctx, err, context.Canceled)
+			}
+			nw.Observer = nil
+			sol, err := nw.Solve()
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkSolution(t, sol, 1999)
+			if pushes := sol.Stats.NumPushes; (pushes == 0) != keep || sol.Stats.NumWarmStarts != 0 {
+				t.Errorf("%+v keep %v: resumed RecoverFlow with %d pushes and %d warm starts", ctx, keep, pushes, sol.Stats.NumWarmStarts)
+			}
+		}
+	}
+}
+
+// cancelIn is an Observer that cancels once phase has started.
+type cancelIn struct {
+	phase  pseudo.Phase
+	cancel context.CancelFunc
+}
+
+func (c *cancelIn) PhaseStarted(p pseudo.Progress) {
+	if p.Phase == c.phase {
+		c.cancel()
+	}
+}
+
+func (c *cancelIn) Progress(pseudo.Progress) {}
+
+func (c *cancelIn) PhaseFinished(pseudo.Progress) {}
+
+// recorder is an Observer that keeps what it is told.
+type recorder struct {
+	events   []string
+	progress []pseudo.Progress
+}
+
+func (r *recorder) PhaseStarted(p pseudo.Progress) {
+	r.events = append(r.events, "start "+p.Phase.String())
+}
+
+func (r *recorder) Progress(p pseudo.Progress) {
+	r.progress = append(r.progress, p)
+}
+
+func (r *recorder) PhaseFinished(p pseudo.Progress) {
+	r.events = append(r.events, "finish "+p.Phase.String())
+}
+
+func TestObserver(t *testing.T) {
+	data := genDimacs(2000, 10000, 1)
+	want := []string{
+		"start reading file", "finish reading file",
+		"start initialization", "finish initialization",
+		"start phase 1", "finish phase 1",
+		"start flow recovery", "finish flow recovery",
+	}
+	for _, ctx := range ctxs {
+		r := new(recorder)
+		nw := &pseudo.Network{Ctx: ctx, Observer: r, ProgressInterval: 64}
+		if err := nw.ReadDimacs(bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		sol, err := nw.Solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(r.events, ", ") != strings.Join(want, ", ") {
+			t.Errorf("%+v: events %q, want %q", ctx, r.events, want)
+		}
+
+		var phaseOne, recover int
+		var last pseudo.Statistics
+		for _, p := range r.progress {
+			switch p.Phase {
+			case pseudo.PhaseOne:
+				phaseOne++
+				if p.Label > 2000 {
+					t.Errorf("%+v: progress label %d", ctx, p.Label)
+				}
+			case pseudo.PhaseRecoverFlow:
+				recover++
+				if p.Nodes > p.NumNodes || p.NumNodes != 2000 {
+					t.Errorf("%+v: progress %d of %d nodes", ctx, p.Nodes, p.NumNodes)
+				}
+			}
+			if p.Stats.NumPushes < last.NumPushes || p.Stats.NumMergers < last.NumMergers {
+				t.Errorf("%+v: statistics go back from %+v to %+v", ctx, last, p.Stats)
+			}
+			last = p.Stats
+		}
+		if phaseOne == 0 || recover == 0 {
+			t.Errorf("%+v: %d phase 1 and %d flow recovery progress calls", ctx, phaseOne, recover)
+		}
+		if sol.Stats.NumPushes < last.NumPushes {
+			t.Errorf("%+v: solution statistics %+v before progress %+v", ctx, sol.Stats, last)
+		}
+	}
+
+	var buf bytes.Buffer
+	nw := &pseudo.Network{Observer: pseudo.ProgressPrinter(&buf), ProgressInterval: 1 << 20}
+	if err := nw.ReadDimacs(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nw.Solve(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "c Finished reading file.\nc Finished initialization.\nc Finished phase 1.\nc Finished flow recovery.\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	nw := &pseudo.Network{Logger: slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))}
+	fh, err := os.Open(maxfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	if err := nw.ReadDimacsFile(fh); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nw.Solve(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`msg="problem line" line=1 type=max nodes=6 arcs=8`,
+		`msg="source node" line=2 node=1`,
+		`msg="sink node" line=3 node=6`,
+		`msg="read network" lines=`,
+		`msg="phase started" phase="phase 1"`,
+		`msg="phase finished" phase="flow recovery"`,
+		`msg="checked optimality" flow=15 mincut=15 feasible=true optimal=true violations=0`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log has no %q:\n%s", want, buf.String())
+		}
+	}
+
+	// Nothing is written to stdout by default.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	_, err = pseudo.Run(maxfFile)
+	os.Stdout = stdout
+	w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := io.ReadAll(r)
+	if len(out) != 0 {
+		t.Errorf("Run printed %q", out)
+	}
+}
+
+func TestMinCut(t *testing.T) {
+	fh, err := os.Open(maxfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+
+	nw := &pseudo.Network{Ctx: pseudo.Context{LowestLabel: true}}
+	if err := nw.ReadDimacsFile(fh); err != nil {
+		t.Fatal(err)
+	}
+	if err := nw.SimpleInitialization(); err != nil {
+		t.Fatal(err)
+	}
+	nw.FlowPhaseOne()
+	cut := nw.MinCut()
+
+	src := make(map[uint]bool)
+	for _, n := range cut.SourceSet {
+		src[n] = true
+	}
+	if !src[1] || src[6] {
+		t.Fatalf("source set %v must hold the source but not the sink", cut.SourceSet)
+	}
+	var capacity int64
+	for _, a := range cut.Arcs {
+		if !src[a.From] || src[a.To] {
+			t.Errorf("arc %d (%d, %d) does not cross the cut", a.ID, a.From, a.To)
+		}
+		capacity += a.Capacity
+	}
+	if capacity != cut.Capacity {
+		t.Errorf("cut capacity %d, want sum of arc capacities %d", cut.Capacity, capacity)
+	}
+	if lines := cut.Dimacs(); len(lines) != 2+len(cut.SourceSet) {
+		t.Errorf("got %d Dimacs lines for %d nodes", len(lines), len(cut.SourceSet))
+	}
+}
+
+// genDimacs returns a random max flow problem with numNodes nodes and about
+// numArcs arcs; node 1 is the source and node numNodes the sink.
+func genDimacs(numNodes, numArcs int, seed int64) []byte {
+	rnd := rand.New(rand.NewSource(seed))
+	var arcs [][3]int
+	for i := 2; i < numNodes; i++ {
+		if rnd.Intn(4) == 0 {
+			arcs = append(arcs, [3]int{1, i, 1 + rnd.Intn(100)})
+		}
+		if rnd.Intn(4) == 0 {
+			arcs = append(arcs, [3]int{i, numNodes, 1 + rnd.Intn(100)})
+		}
+	}
+	for len(arcs) < numArcs {
+		from, to := 2+rnd.Intn(numNodes-2), 2+rnd.Intn(numNodes-2)
+		if from != to {
+			arcs = append(arcs, [3]int{from, to, 1 + rnd.Intn(100)})
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "c random instance, seed %d\np max %d %d\nn 1 s\nn %d t\n", seed, numNodes, len(arcs), numNodes)
+	for _, a := range arcs {
+		fmt.Fprintf(&buf, "a %d %d %d\n", a[0], a[1], a[2])
+	}
+	return buf.Bytes()
+}
+
+func benchmarkSolve(b *testing.B, minCut bool) {
+	data := genDimacs(20000, 200000, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		nw := &pseudo.Network{Ctx: pseudo.Context{LowestLabel: true}}
+		if err := nw.ReadDimacs(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		var err error
+		if minCut {
+			_, err = nw.SolveMinCut()
+		} else {
+			_, err = nw.Solve()
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSolve and BenchmarkSolveMinCut compare the full pipeline
+// with the phase one only min cut on the same instance.
+func BenchmarkSolve(b *testing.B)       { benchmarkSolve(b, false) }
+func BenchmarkSolveMinCut(b *testing.B) { benchmarkSolve(b, true) }
+
+// sscanfDimacs reads data into a Network line by line with fmt.Sscanf,
+// as ReadDimacs did before its tokenizer, for BenchmarkReadDimacsSscanf.
+func sscanfDimacs(data []byte) (*pseudo.Network, error) {
+	var nw *pseudo.Network
+	var ch, word, st pseudo.AlphaString
+	var numNodes, numArcs, from, to, i uint
+	var capacity int64
+	buf := bufio.NewReader(bytes.NewReader(data))
+	for {
+		line, err := buf.ReadBytes('\n')
+		if len(line) == 0 {
+			if err == io.EOF {
+				return nw, nil
+			}
+			return nil, err
+		}
+		switch line[0] {
+		case 'p':
+			if _, err := fmt.Sscanf(string(line), "%v %s %d %d", &ch, &word, &numNodes, &numArcs); err != nil {
+				return nil, err
+			}
+			nw = pseudo.NewNetwork(numNodes)
+		case 'n':
+			if _, err := fmt.Sscanf(string(line), "%v %d %v", &ch, &i, &st); err != nil {
+				return nil, err
+			}
+			if st == "s" {
+				nw.SetSource(i)
+			} else {
+				nw.SetSink(i)
+			}
+		case 'a':
+			if _, err := fmt.Sscanf(string(line), "%v %d %d %v", &ch, &from, &to, &capacity); err != nil {
+				return nil, err
+			}
+			nw.AddArc(from, to, capacity)
+		}
+	}
+}
+
+func benchmarkReadDimacs(b *testing.B, read func([]byte) error) {
+	data := genDimacs(100000, 1000000, 1)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := read(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadDimacs(b *testing.B) {
+	benchmarkReadDimacs(b, func(data []byte) error {
+		return new(pseudo.Network).ReadDimacs(bytes.NewReader(data))
+	})
+}
+
+func BenchmarkReadDimacsSscanf(b *testing.B) {
+	benchmarkReadDimacs(b, func(data []byte) error {
+		_, err := sscanfDimacs(data)
+		return err
+	})
+}
+
+func TestReadDimacsAllocs(t *testing.T) {
+	data := genDimacs(1000, 100000, 1)
+	want, err := sscanfDimacs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wsol, err := want.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nw := new(pseudo.Network)
+	allocs := testing.AllocsPerRun(5, func() {
+		if err := nw.ReadDimacs(bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+	})
+	// a few per node and per slab of arcs, none per line
+	if allocs > 5000 {
+		t.Errorf("%v allocations reading %d lines", allocs, bytes.Count(data, []byte("\n")))
+	}
+	sol, err := nw.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sol.Flow != wsol.Flow {
+		t.Errorf("flow %d, want %d", sol.Flow, wsol.Flow)
+	}
+
+	// the capacities in quarters, as decimals
+	var fdata []byte
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		var from, to uint
+		var c int64
+		if _, err := fmt.Sscanf(string(line), "a %d %d %d", &from, &to, &c); err == nil {
+			line = fmt.Appendf(nil, "a %d %d %d.%02d\n", from, to, c/4, c%4*25)
+		}
+		fdata = append(fdata, line...)
+	}
+	fnw := new(pseudo.NetworkOf[float64])
+	allocs = testing.AllocsPerRun(5, func() {
+		if err := fnw.ReadDimacs(bytes.NewReader(fdata)); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 5000 {
+		t.Errorf("%v allocations reading %d lines of float64 capacities", allocs, bytes.Count(fdata, []byte("\n")))
+	}
+	fsol, err := fnw.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fsol.Flow*4 != float64(wsol.Flow) {
+		t.Errorf("float64 flow %v, want %v", fsol.Flow, float64(wsol.Flow)/4)
+	}
+}
+
+func TestSolveMinCut(t *testing.T) {
+	defer func(ctx pseudo.Context) { pseudo.PseudoCtx = ctx }(pseudo.PseudoCtx)
+	pseudo.PseudoCtx = pseudo.Context{LowestLabel: true}
+
+	cut, err := pseudo.SolveMinCut(maxfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cut.SourceSet) == 0 || cut.SourceSet[0] != 1 {
+		t.Errorf("source set %v does not hold the source", cut.SourceSet)
+	}
+}
+
+// checkSolution verifies sol as a maximum flow of value want.
+func checkSolution(t *testing.T, sol *pseudo.Solution, want int64) {
+	t.Helper()
+	if !sol.Feasible || !sol.Optimal {
+		t.Errorf("feasible %v, optimal %v: %v", sol.Feasible, sol.Optimal, sol.Violations)
+	}
+	if sol.Flow != want || sol.Cut.Capacity != want {
+		t.Errorf("flow %d, cut capacity %d, want %d", sol.Flow, sol.Cut.Capacity, want)
+	}
+	for _, a := range sol.Arcs {
+		if a.Flow < 0 || a.Flow > a.Capacity {
+			t.Errorf("arc %d (%d, %d): flow %d, capacity %d", a.ID, a.From, a.To, a.Flow, a.Capacity)
+		}
+	}
+}
+
+// freshNetwork returns a network of numNodes nodes, source 1 and the given
+// sink, with arcs added in order.
+func freshNetwork(ctx pseudo.Context, numNodes, sink uint, arcs []pseudo.ArcFlow) *pseudo.Network {
+	nw := pseudo.NewNetwork(numNodes)
+	nw.Ctx = ctx
+	nw.SetSource(1)
+	nw.SetSink(sink)
+	for _, a := range arcs {
+		nw.AddArc(a.From, a.To, a.Capacity)
+	}
+	return nw
+}
+
+func TestSolve(t *testing.T) {
+	for _, ctx := range ctxs {
+		fh, err := os.Open(maxfFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nw := &pseudo.Network{Ctx: ctx}
+		err = nw.ReadDimacsFile(fh)
+		fh.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sol, err := nw.Solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSolution(t, sol, 15)
+	}
+}
+
+func TestSolveRandom(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		data := genDimacs(200, 1000, seed)
+		var flow []int64
+		for _, ctx := range ctxs {
+			nw := &pseudo.Network{Ctx: ctx}
+			if err := nw.ReadDimacs(bytes.NewReader(data)); err != nil {
+				t.Fatal(err)
+			}
+			sol, err := nw.Solve()
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkSolution(t, sol, sol.Cut.Capacity)
+			flow = append(flow, sol.Flow)
+		}
+		for i := range flow {
+			if flow[i] != flow[0] {
+				t.Errorf("seed %d: flows %v differ between contexts", seed, flow)
+				break
+			}
+		}
+	}
+}
+
+func TestNetworkOfFloat(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		data := genDimacs(200, 1000, seed)
+		nw := &pseudo.Network{Ctx: pseudo.Context{LowestLabel: true}}
+		if err := nw.ReadDimacs(bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		sol, err := nw.Solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the same network with capacities in thirds, which are not exact in float64
+		for _, ctx := range ctxs {
+			fnw := pseudo.NewNetworkOf[float64](200)
+			fnw.Ctx = ctx
+			fnw.SetSource(1)
+			fnw.SetSink(200)
+			for _, a := range sol.Arcs {
+				fnw.AddArc(a.From, a.To, float64(a.Capacity)/3)
+			}
+			fsol, err := fnw.Solve()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !fsol.Feasible || !fsol.Optimal {
+				t.Errorf("seed %d: feasible %v, optimal %v: %v", seed, fsol.Feasible, fsol.Optimal, fsol.Violations)
+			}
+			if want := float64(sol.Flow) / 3; math.Abs(fsol.Flow-want) > 1e-6 {
+				t.Errorf("seed %d: flow %v, want %v", seed, fsol.Flow, want)
+			}
+		}
+	}
+}
+
+func TestNetworkOfFloatDimacs(t *testing.T) {
+	data := "p max 4 5\nn 1 s\nn 4 t\na 1 2 0.5\na 1 3 1.25\na 2 3 0.1\na 2 4 0.3\na 3 4 2.5\n"
+	nw := &pseudo.NetworkOf[float64]{Epsilon: pseudo.DefaultEpsilon}
+	if err := nw.ReadDimacs(strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	sol, err := nw.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sol.Optimal || math.Abs(sol.Flow-1.65) > 1e-9 {
+		t.Errorf("flow %v, optimal %v, want 1.65", sol.Flow, sol.Optimal)
+	}
+}
+
+// floatDimacs returns data with each capacity c written as the float64 c/div.
+func floatDimacs(data []byte, div float64) []byte {
+	var ret []byte
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		var from, to uint
+		var c int64
+		if _, err := fmt.Sscanf(string(line), "a %d %d %d", &from, &to, &c); err == nil {
+			line = fmt.Appendf(nil, "a %d %d %v\n", from, to, float64(c)/div)
+		}
+		ret = append(ret, line...)
+	}
+	return ret
+}
+
+func TestNetworkOfFloatZeroValue(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		data := genDimacs(200, 1500, seed)
+		want, err := sscanfDimacs(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wsol, err := want.Solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fdata := floatDimacs(data, 70)
+		for _, ctx := range ctxs {
+			nw := &pseudo.NetworkOf[float64]{Ctx: ctx}
+			if err := nw.ReadDimacs(bytes.NewReader(fdata)); err != nil {
+				t.Fatal(err)
+			}
+			if nw.Epsilon != pseudo.DefaultEpsilon {
+				t.Errorf("seed %d %+v: Epsilon %v, want DefaultEpsilon", seed, ctx, nw.Epsilon)
+			}
+			sol, err := nw.Solve()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sol.Feasible || !sol.Optimal || math.Abs(sol.Flow-float64(wsol.Flow)/70) > 1e-6 {
+				t.Errorf("seed %d %+v: flow %v, feasible %v, optimal %v, want %v", seed, ctx, sol.Flow, sol.Feasible, sol.Optimal, float64(wsol.Flow)/70)
+			}
+
+			// too small an Epsilon for the rounding leaves excess that
+			// RecoverFlow cannot carry back, which it must leave alone
+			nw = &pseudo.NetworkOf[float64]{Ctx: ctx, Epsilon: 1e-300}
+			if err := nw.ReadDimacs(bytes.NewReader(fdata)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := nw.Solve(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestSolveMinCost(t *testing.T) {
+	// The solution vector given in the file is [2,2,2,0,4] with cost at 14.
+	sol, err := pseudo.SolveMinCost("examples/dimacsMcf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sol.Cost != 14 {
+		t.Errorf("cost %d, want 14", sol.Cost)
+	}
+	for i, want := range []int64{2, 2, 2, 0, 4} {
+		if got := sol.Arcs[i].Flow; got != want {
+			t.Errorf("arc %d: flow %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestMinCostBounds(t *testing.T) {
+	// Shipping 3 units from 1 to 4; arc 1->2 has a lower bound of 1
+	// and arc 2->3 a negative cost.
+	m := pseudo.NewMinCostNetwork(4)
+	m.SetSupply(1, 3)
+	m.SetSupply(4, -3)
+	m.AddArc(1, 2, 1, 3, 4)
+	m.AddArc(1, 3, 0, 3, 1)
+	m.AddArc(2, 3, 0, 2, -2)
+	m.AddArc(2, 4, 0, 3, 1)
+	m.AddArc(3, 4, 0, 2, 1)
+	sol, err := m.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1->3->4 carries 2 at cost 2 each, the last unit goes 1->2->4 at cost 5.
+	if sol.Cost != 9 {
+		t.Errorf("cost %d, want 9: %v", sol.Cost, sol.Arcs)
+	}
+
+	m.SetSupply(1, 6)
+	m.SetSupply(4, -6)
+	if _, err := m.Solve(); err == nil {
+		t.Error("no error for supply above the cut capacity")
+	}
+}
+
+func TestReadMinCostErrors(t *testing.T) {
+	for _, tc := range []struct {
+		data         string
+		line, column uint
+		msg          string
+	}{
+		{"c no problem\n", 1, 0, "no problem line"},
+		{"n 1 4\n", 1, 1, `"n" line before problem line`},
+		{"p max 4 1\n", 1, 3, `problem type "max" is not min`},
+		{"p min 4 1\nn 5 4\n", 2, 3, "node 5 out of range 1..4"},
+		{"p min 4 1\nn 1 x\n", 2, 5, `supply "x": invalid syntax`},
+		{"p min 4 1\na 1 2 0 4\n", 2, 10, "missing cost"},
+		{"p min 4 1\na 1 2 0 4 2 9\n", 2, 13, `unexpected "9"`},
+		{"p min 4 1\na 1 2 0 4 2\na 1 3 0 4 2\n", 3, 1, "more arc lines than the 1 of the problem line"},
+		{"p min 4 2\nc\na 1 2 0 4 2\n", 3, 0, "1 arc lines, the problem line has 2"},
+	} {
+		_, err := pseudo.ReadMinCost(strings.NewReader(tc.data), 0)
+		var perr *pseudo.ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: error %v, want a *ParseError", tc.data, err)
+			continue
+		}
+		if perr.Line != tc.line || perr.Column != tc.column || perr.Msg != tc.msg {
+			t.Errorf("%q: error %+v, want line %d, column %d: %s", tc.data, *perr, tc.line, tc.column, tc.msg)
+		}
+	}
+
+	// the node limit
+	var perr *pseudo.ParseError
+	_, err := pseudo.ReadMinCost(strings.NewReader("p min 5 0\n"), 4)
+	if !errors.As(err, &perr) || perr.Msg != "5 nodes, more than the limit of 4" {
+		t.Errorf("5 nodes, limit 4: error %v", err)
+	}
+}
+
+func TestMinCostOverflow(t *testing.T) {
+	// The lower bound costs more than an int64 holds.
+	m := pseudo.NewMinCostNetwork(2)
+	m.AddArc(1, 2, 1<<32, 1<<32, 1<<32)
+	if _, err := m.Solve(); err == nil {
+		t.Error("no error for the cost of a lower bound overflowing")
+	}
+
+	// So does the flow shipped along the shortest path.
+	m = pseudo.NewMinCostNetwork(2)
+	m.SetSupply(1, 1<<40)
+	m.SetSupply(2, -1<<40)
+	m.AddArc(1, 2, 0, 1<<40, 1<<30)
+	if _, err := m.Solve(); err == nil {
+		t.Error("no error for the cost of a path overflowing")
+	}
+	m.AddArc(1, 2, 0, 1<<40, 1)
+	if sol, err := m.Solve(); err != nil || sol.Cost != 1<<40 {
+		t.Errorf("cost %v, error %v, want %d on the cheap arc", sol, err, int64(1<<40))
+	}
+}
+
+func TestSolveAssignment(t *testing.T) {
+	// The optimal assignment given in the file is 1-5, 2-6, 3-4 with cost at 6.
+	asn, err := pseudo.SolveAssignment("examples/dimacsAsn.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asn.Cost != 6 {
+		t.Errorf("cost %d, want 6", asn.Cost)
+	}
+	want := [][2]uint{{1, 5}, {2, 6}, {3, 4}}
+	if len(asn.Arcs) != len(want) {
+		t.Fatalf("assignment %v, want %v", asn.Arcs, want)
+	}
+	for i, a := range asn.Arcs {
+		if a.From != want[i][0] || a.To != want[i][1] {
+			t.Errorf("assignment %v, want %v", asn.Arcs, want)
+			break
+		}
+	}
+}
+
+func TestReadAssignmentErrors(t *testing.T) {
+	for _, tc := range []struct {
+		data         string
+		line, column uint
+		msg          string
+	}{
+		{"", 0, 0, "no problem line"},
+		{"c\na 1 4 5\n", 2, 1, `"a" line before problem line`},
+		{"p asn 6\n", 1, 8, "missing number of arcs"},
+		{"p min 6 1\n", 1, 3, `problem type "min" is not asn`},
+		{"p asn 6 1\nn 1 s\n", 2, 5, `unexpected "s"`},
+		{"p asn 6 1\nn 1\n\na 1 7 5\n", 4, 5, "to node 7 out of range 1..6"},
+		{"p asn 6 1\nn 1\na 1 4 five\n", 3, 7, `cost "five": invalid syntax`},
+		{"p asn 6 1\nn 1\na 1 4 5\nx 2\n", 4, 1, `unknown line type "x"`},
+		{"p asn 6 2\nn 1\na 1 4 5\n", 3, 0, "1 arc lines, the problem line has 2"},
+	} {
+		_, err := pseudo.ReadAssignment(strings.NewReader(tc.data), 0)
+		var perr *pseudo.ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: error %v, want a *ParseError", tc.data, err)
+			continue
+		}
+		if perr.Line != tc.line || perr.Column != tc.column || perr.Msg != tc.msg {
+			t.Errorf("%q: error %+v, want line %d, column %d: %s", tc.data, *perr, tc.line, tc.column, tc.msg)
+		}
+	}
+
+	// the node limit
+	var perr *pseudo.ParseError
+	_, err := pseudo.ReadAssignment(strings.NewReader("p asn 5 0\n"), 4)
+	if !errors.As(err, &perr) || perr.Msg != "5 nodes, more than the limit of 4" {
+		t.Errorf("5 nodes, limit 4: error %v", err)
+	}
+}
+
+func TestMaxMatching(t *testing.T) {
+	defer func(ctx pseudo.Context) { pseudo.PseudoCtx = ctx }(pseudo.PseudoCtx)
+
+	// Nodes 1-4 on the source side, 5-8 on the other; 1 and 2 can only
+	// be matched to 5, so the largest matching has 3 arcs.
+	p := pseudo.NewAssignmentProblem(8)
+	for n := uint(1); n <= 4; n++ {
+		p.SetSource(n)
+	}
+	for _, a := range [][2]uint{{1, 5}, {2, 5}, {3, 5}, {3, 6}, {4, 6}, {4, 7}, {4, 8}} {
+		p.AddArc(a[0], a[1], 0)
+	}
+	if _, err := p.Solve(); err == nil {
+		t.Error("no error for a problem without a complete assignment")
+	}
+
+	for _, ctx := range ctxs {
+		pseudo.PseudoCtx = ctx
+		m, err := p.MaxMatching()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m.Arcs) != 3 {
+			t.Errorf("%+v: matching %v, want 3 arcs", ctx, m.Arcs)
+		}
+		from, to := make(map[uint]bool), make(map[uint]bool)
+		for _, a := range m.Arcs {
+			if from[a.From] || to[a.To] {
+				t.Errorf("%+v: matching %v reuses a node", ctx, m.Arcs)
+			}
+			from[a.From], to[a.To] = true, true
+		}
+	}
+}
+
+func TestSolveParametric(t *testing.T) {
+	// capacity scales the arcs out of the source up, and those into the
+	// sink down, with lambda.
+	capacity := func(a pseudo.ArcFlow, lambda float64) int64 {
+		switch {
+		case a.From == 1:
+			return int64(float64(a.Capacity) * lambda / 4)
+		case a.To == 200:
+			return int64(float64(a.Capacity) * (10 - lambda) / 4)
+		}
+		return a.Capacity
+	}
+	var lambdas []float64
+	for l := 0.0; l <= 10; l += 0.25 {
+		lambdas = append(lambdas, l)
+	}
+
+	for seed := int64(1); seed <= 5; seed++ {
+		data := genDimacs(200, 1000, seed)
+		base := &pseudo.Network{}
+		if err := base.ReadDimacs(bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		sol, err := base.Solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		arcs := sol.Arcs
+		byID := make([]pseudo.ArcFlow, len(arcs))
+		for _, a := range arcs {
+			byID[a.ID] = a
+		}
+
+		for _, ctx := range ctxs {
+			nw := &pseudo.Network{Ctx: ctx}
+			if err := nw.ReadDimacs(bytes.NewReader(data)); err != nil {
+				t.Fatal(err)
+			}
+			bps, err := nw.SolveParametric(lambdas, func(id pseudo.ArcID, lambda float64) int64 {
+				return capacity(byID[id], lambda)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(bps) < 2 {
+				t.Errorf("seed %d %+v: %d breakpoints", seed, ctx, len(bps))
+			}
+
+			// Each lambda must have the min cut of a fresh solve; the
+			// source set in force is that of the last breakpoint at or before it.
+			k := 0
+			for _, lambda := range lambdas {
+				for k+1 < len(bps) && bps[k+1].Lambda <= lambda {
+					k++
+				}
+				src := make(map[uint]bool)
+				for _, n := range bps[k].SourceSet {
+					src[n] = true
+				}
+				arcs := make([]pseudo.ArcFlow, len(byID))
+				var cut int64
+				for i, a := range byID {
+					arcs[i] = a
+					arcs[i].Capacity = capacity(a, lambda)
+					if src[a.From] && !src[a.To] {
+						cut += arcs[i].Capacity
+					}
+				}
+				want, err := freshNetwork(pseudo.Context{}, 200, 200, arcs).SolveMinCut()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if cut != want.Capacity {
+					t.Errorf("seed %d %+v: lambda %v: cut %d, want %d", seed, ctx, lambda, cut, want.Capacity)
+				}
+			}
+			for k := 1; k < len(bps); k++ {
+				next := make(map[uint]bool)
+				for _, n := range bps[k].SourceSet {
+					next[n] = true
+				}
+				for _, n := range bps[k-1].SourceSet {
+					if !next[n] {
+						t.Errorf("seed %d %+v: source sets at %v and %v are not nested", seed, ctx, bps[k-1].Lambda, bps[k].Lambda)
+						break
+					}
+				}
+			}
+
+			// the flow at the last lambda
+			nw.RecoverFlow()
+			if res := strings.Join(nw.Result(""), "\n"); !strings.Contains(res, "checks as feasible") || !strings.Contains(res, "checks as optimal") {
+				t.Errorf("seed %d %+v: flow at lambda %v is not optimal", seed, ctx, lambdas[len(lambdas)-1])
+			}
+		}
+	}
+}
+
+func TestSolveParametricBreakpoints(t *testing.T) {
+	// Nodes 2 and 3 cross to the source side at lambda 1.5 and 3.
+	nw := pseudo.NewNetworkOf[float64](4)
+	nw.SetSource(1)
+	nw.SetSink(4)
+	nw.AddArc(1, 2, 0)
+	nw.AddArc(1, 3, 0)
+	nw.AddArc(2, 4, 0)
+	nw.AddArc(3, 4, 0)
+	nw.AddArc(1, 4, 0)
+	bps, err := nw.SolveParametric([]float64{0, 5}, func(id pseudo.ArcID, lambda float64) float64 {
+		return [...]float64{lambda, 2 * lambda, 6 - lambda, 6 - 2*lambda, lambda}[id]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		lambda float64
+		source []uint
+	}{{0, []uint{1}}, {1.5, []uint{1, 3}}, {3, []uint{1, 2, 3}}}
+	if len(bps) != len(want) {
+		t.Fatalf("%d breakpoints, want %d", len(bps), len(want))
+	}
+	for k, bp := range bps {
+		if math.Abs(bp.Lambda-want[k].lambda) > 1e-8 || fmt.Sprint(bp.SourceSet) != fmt.Sprint(want[k].source) {
+			t.Errorf("breakpoint %d at %v, source set %v, want %v, %v", k, bp.Lambda, bp.SourceSet, want[k].lambda, want[k].source)
+		}
+	}
+
+	// an arc from the source to the sink must not decrease either
+	nw = pseudo.NewNetworkOf[float64](2)
+	nw.SetSource(1)
+	nw.SetSink(2)
+	nw.AddArc(1, 2, 0)
+	if _, err := nw.SolveParametric([]float64{0, 1}, func(id pseudo.ArcID, lambda float64) float64 {
+		return 1 - lambda
+	}); err == nil {
+		t.Error("no error for a decreasing arc from the source to the sink")
+	}
+
+	// The grid holds only the ends of the range; each breakpoint must be
+	// minimum at its lambda, and the one before it just below.
+	capacity := func(a pseudo.ArcFlow, lambda float64) int64 {
+		switch {
+		case a.From == 1:
+			return int64(float64(a.Capacity) * lambda / 4)
+		case a.To == 200:
+			return int64(float64(a.Capacity) * (10 - lambda) / 4)
+		}
+		return a.Capacity
+	}
+	at := func(arcs []pseudo.ArcFlow, lambda float64) []pseudo.ArcFlow {
+		out := make([]pseudo.ArcFlow, len(arcs))
+		for i, a := range arcs {
+			out[i] = a
+			out[i].Capacity = capacity(a, lambda)
+		}
+		return out
+	}
+	cutOf := func(arcs []pseudo.ArcFlow, source []uint) int64 {
+		src := make(map[uint]bool)
+		for _, n := range source {
+			src[n] = true
+		}
+		var cut int64
+		for _, a := range arcs {
+			if src[a.From] && !src[a.To] {
+				cut += a.Capacity
+			}
+		}
+		return cut
+	}
+	for seed := int64(1); seed <= 3; seed++ {
+		data := genDimacs(200, 1000, seed)
+		for _, ctx := range ctxs {
+			nw := &pseudo.Network{Ctx: ctx}
+			if err := nw.ReadDimacs(bytes.NewReader(data)); err != nil {
+				t.Fatal(err)
+			}
+			base := &pseudo.Network{}
+			if err := base.ReadDimacs(bytes.NewReader(data)); err != nil {
+				t.Fatal(err)
+			}
+			sol, err := base.Solve()
+			if err != nil {
+				t.Fatal(err)
+			}
+			arcs := make([]pseudo.ArcFlow, len(sol.Arcs))
+			for _, a := range sol.Arcs {
+				arcs[a.ID] = a
+			}
+			bps, err := nw.SolveParametric([]float64{0, 10}, func(id pseudo.ArcID, lambda float64) int64 {
+				return capacity(arcs[id], lambda)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(bps) < 3 {
+				t.Errorf("seed %d %+v: %d breakpoints", seed, ctx, len(bps))
+			}
+			for k, bp := range bps {
+				want, err := freshNetwork(ctx, 200, 200, at(arcs, bp.Lambda)).SolveMinCut()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if bp.Capacity != want.Capacity || cutOf(at(arcs, bp.Lambda), bp.SourceSet) != want.Capacity {
+					t.Errorf("seed %d %+v: lambda %v: cut %d, want %d", seed, ctx, bp.Lambda, bp.Capacity, want.Capacity)
+				}
+				if k == 0 {
+					continue
+				}
+				below := at(arcs, math.Nextafter(bp.Lambda, math.Inf(-1)))
+				if want, err = freshNetwork(ctx, 200, 200, below).SolveMinCut(); err != nil {
+					t.Fatal(err)
+				}
+				if prev := cutOf(below, bps[k-1].SourceSet); prev != want.Capacity || cutOf(below, bp.SourceSet) == want.Capacity {
+					t.Errorf("seed %d %+v: breakpoint %v is not the least lambda of its cut", seed, ctx, bp.Lambda)
+				}
+			}
+		}
+	}
+}
+
+func TestUpdateCapacity(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		data := genDimacs(200, 1000, seed)
+		rnd := rand.New(rand.NewSource(seed))
+		for _, ctx := range ctxs {
+			nw := &pseudo.Network{Ctx: ctx}
+			if err := nw.ReadDimacs(bytes.NewReader(data)); err != nil {
+				t.Fatal(err)
+			}
+			sol, err := nw.Solve()
+			if err != nil {
+				t.Fatal(err)
+			}
+			arcs := make([]pseudo.ArcFlow, len(sol.Arcs))
+			for _, a := range sol.Arcs {
+				arcs[a.ID] = a
+			}
+
+			var warm, restarts uint
+			for round := 0; round < 10; round++ {
+				// a few edits, some of them on the arcs of the cut
+				for k := 0; k < 5; k++ {
+					id := pseudo.ArcID(rnd.Intn(len(arcs)))
+					if k < 2 && len(sol.Cut.Arcs) > 0 {
+						id = sol.Cut.Arcs[rnd.Intn(len(sol.Cut.Arcs))].ID
+					}
+					arcs[id].Capacity = int64(rnd.Intn(150))
+					if err := nw.UpdateCapacity(id, arcs[id].Capacity); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if sol, err = nw.Solve(); err != nil {
+					t.Fatal(err)
+				}
+				warm += sol.Stats.NumWarmStarts
+				restarts += sol.Stats.NumRestarts
+
+				want, err := freshNetwork(ctx, 200, 200, arcs).Solve()
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkSolution(t, sol, want.Flow)
+				for _, a := range sol.Arcs {
+					if a.Capacity != arcs[a.ID].Capacity {
+						t.Fatalf("arc %d: capacity %d, want %d", a.ID, a.Capacity, arcs[a.ID].Capacity)
+					}
+				}
+			}
+			// the first RecoverFlow kept no phase one state to continue
+			if warm != 9 {
+				t.Errorf("seed %d %+v: %d warm starts, want 9", seed, ctx, warm)
+			}
+			// a continued solve seldom has to start over
+			if restarts > 2 {
+				t.Errorf("seed %d %+v: %d of %d warm starts restarted", seed, ctx, restarts, warm)
+			}
+		}
+	}
+
+	nw := pseudo.NewNetwork(2)
+	id := nw.AddArc(1, 2, 1)
+	if err := nw.UpdateCapacity(id+1, 1); err == nil {
+		t.Error("no error for an unknown arc")
+	}
+	if err := nw.UpdateCapacity(id, -1); err == nil {
+		t.Error("no error for a negative capacity")
+	}
+}
+
+func TestWarmStart(t *testing.T) {
+	data, err := os.ReadFile(maxfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ctx := range ctxs {
+		for _, keep := range []bool{false, true} {
+			nw := &pseudo.Network{Ctx: ctx, KeepPhaseOne: keep}
+			if err := nw.ReadDimacs(bytes.NewReader(data)); err != nil {
+				t.Fatal(err)
+			}
+			for i, tc := range []struct {
+				arc      pseudo.ArcID
+				capacity int64
+				flow     int64
+			}{
+				{0, 5, 15},
+				{4, 10, 20},
+				{4, 5, 15},
+				{1, 20, 15},
+				{4, 8, 18},
+			} {
+				if i > 0 {
+					if err := nw.UpdateCapacity(tc.arc, tc.capacity); err != nil {
+						t.Fatal(err)
+					}
+				}
+				sol, err := nw.Solve()
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkSolution(t, sol, tc.flow)
+				// the state of the first solve is kept only if asked for
+				var warm uint
+				if i > 1 || (i == 1 && keep) {
+					warm = 1
+				}
+				if sol.Stats.NumWarmStarts != warm || sol.Stats.NumRestarts != 0 {
+					t.Errorf("%+v keep %v: solve %d: %d warm starts and %d restarts, want %d and 0", ctx, keep, i, sol.Stats.NumWarmStarts, sol.Stats.NumRestarts, warm)
+				}
+				// a solve after the first times no read
+				if i > 0 && sol.Timings.ReadDimacsFile != 0 {
+					t.Errorf("%+v keep %v: solve %d: read time %v", ctx, keep, i, sol.Timings.ReadDimacsFile)
+				}
+			}
+		}
+	}
+}
+
+func TestAddArcNode(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		data := genDimacs(200, 1000, seed)
+		rnd := rand.New(rand.NewSource(seed))
+		for _, ctx := range ctxs {
+			nw := &pseudo.Network{Ctx: ctx}
+			if err := nw.ReadDimacs(bytes.NewReader(data)); err != nil {
+				t.Fatal(err)
+			}
+			sol, err := nw.Solve()
+			if err != nil {
+				t.Fatal(err)
+			}
+			arcs := make([]pseudo.ArcFlow, len(sol.Arcs))
+			for _, a := range sol.Arcs {
+				arcs[a.ID] = a
+			}
+
+			numNodes := uint(200)
+			for round := 0; round < 10; round++ {
+				// a new node, with arcs from the source, into the sink
+				// and between nodes old and new
+				if round%2 == 0 {
+					if n := nw.AddNode(); n != numNodes+1 {
+						t.Fatalf("AddNode %d, want %d", n, numNodes+1)
+					}
+					numNodes++
+				}
+				for k := 0; k < 8; k++ {
+					from, to := 2+uint(rnd.Intn(int(numNodes-2))), 2+uint(rnd.Intn(int(numNodes-2)))
+					switch k {
+					case 0:
+						from = 1
+					case 1:
+						to = 200
+					case 2:
+						from = numNodes
+					case 3:
+						to = numNodes
+					}
+					capacity := int64(rnd.Intn(100))
+					id := nw.AddArc(from, to, capacity)
+					if id != pseudo.ArcID(len(arcs)) {
+						t.Fatalf("AddArc %d, want %d", id, len(arcs))
+					}
+					arcs = append(arcs, pseudo.ArcFlow{ID: id, From: from, To: to, Capacity: capacity})
+				}
+				if sol, err = nw.Solve(); err != nil {
+					t.Fatal(err)
+				}
+
+				want, err := freshNetwork(ctx, numNodes, 200, arcs).Solve()
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkSolution(t, sol, want.Flow)
+				if len(sol.Arcs) != len(arcs) {
+					t.Fatalf("%d arcs, want %d", len(sol.Arcs), len(arcs))
+				}
+			}
+		}
+	}
+
+	// a negative capacity after a solve is reported by the next one
+	nw := pseudo.NewNetwork(2)
+	nw.SetSource(1)
+	nw.SetSink(2)
+	nw.AddArc(1, 2, 1)
+	if _, err := nw.Solve(); err != nil {
+		t.Fatal(err)
+	}
+	nw.AddArc(1, nw.AddNode(), -1)
+	if _, err := nw.Solve(); err == nil {
+		t.Error("no error for a negative capacity")
+	}
+}
@@ -0,0 +1,356 @@
+// result.go - stream the result of a solve to an io.Writer as Dimacs text, JSON or CSV.
+
+package pseudo
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+)
+
+// ResultFormat is the format of the output of WriteResult.
+type ResultFormat int
+
+const (
+	// FormatDimacs is the Dimacs text returned by Result.
+	FormatDimacs ResultFormat = iota
+	// FormatJSON is a JSON object of the flow value, the checks, the
+	// objects of ConfigJSON, StatsJSON and TimerJSON, the arc flows and
+	// the min cut:
+	//
+	//	{"flow":15,"feasible":true,"optimal":true,
+	//	 "config":{"LowestLabel":true,...},"stats":{"numPushes":5,...},"timings":{"ReadDimacsFile":41250,...},
+	//	 "cut":{"sourceSet":[1,3],"capacity":15},
+	//	 "arcs":[{"id":0,"from":1,"to":2,"flow":5,"capacity":5},...]}
+	FormatJSON
+	// FormatCSV is a CSV table of the arc flows with a header line:
+	//
+	//	id,from,to,flow,capacity
+	//	0,1,2,5,5
+	//	...
+	FormatCSV
+)
+
+var resultFormats = []string{FormatDimacs: "dimacs", FormatJSON: "json", FormatCSV: "csv"}
+
+func (f ResultFormat) String() string {
+	if f >= 0 && int(f) < len(resultFormats) {
+		return resultFormats[f]
+	}
+	return fmt.Sprintf("ResultFormat(%d)", int(f))
+}
+
+// ParseResultFormat returns the ResultFormat named s: "dimacs", "json" or "csv".
+func ParseResultFormat(s string) (ResultFormat, error) {
+	for f, name := range resultFormats {
+		if strings.EqualFold(s, name) {
+			return ResultFormat(f), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown result format %q", s)
+}
+
+// ResultOptions select the lines written by WriteResult.
+type ResultOptions struct {
+	// Format is the format of the result, FormatDimacs by default.
+	Format ResultFormat
+	// Header is the text of the first comment line of FormatDimacs,
+	// as for Result.
+	Header string
+	// OmitZeroFlow leaves out the arcs whose flow is 0, to within the
+	// Epsilon of the Network, and OmitArcs all the arcs.
+	OmitZeroFlow bool
+	OmitArcs     bool
+	// FlowLine is the type of the flow lines of FormatDimacs: 'f' for
+	// "f SRC DST FLOW", as Result writes them, or 'a' for the
+	// "a SRC DST FLOW" of C source displayFlow. 0 is 'f'.
+	FlowLine byte
+}
+
+// WriteResult calls WriteResult on the Network solved by Run or
+// the other package-level functions; it fails if none has been solved.
+func WriteResult(w io.Writer, opts ResultOptions) error {
+	return getStd().WriteResult(w, opts)
+}
+
+// WriteResult writes the result of nw, after RecoverFlow, to w in the
+// format of opts. FormatDimacs is as Result returns it, but it is written,
+// as the other formats, a line at a time through a buffer rather than
+// building the lines, or a Solution, in memory. The arcs are in ArcID order.
+// It fails if RecoverFlow has not finished since the last FlowPhaseOne, as
+// after SolveMinCut or a change to nw, for the arc flows are then not a flow.
+func (nw *NetworkOf[T]) WriteResult(w io.Writer, opts ResultOptions) error {
+	if !nw.solved {
+		return fmt.Errorf("network has no recovered flow")
+	}
+	opt := nw.checkOptimality()
+	r := &resultOf[T]{
+		ctx:        nw.Ctx,
+		epsilon:    nw.Epsilon,
+		flow:       opt.flow,
+		feasible:   opt.feasible,
+		optimal:    opt.optimal,
+		violations: opt.violations,
+		stats:      nw.stats,
+		timings:    nw.timings(),
+		arcs: func(yield func(ArcFlowOf[T]) bool) {
+			for _, a := range nw.arcs {
+				if !yield(ArcFlowOf[T]{ID: a.id, From: a.from.number, To: a.to.number, Flow: a.flow, Capacity: a.capacity}) {
+					return
+				}
+			}
+		},
+	}
+	if nw.Ctx.DisplayCut || opts.Format == FormatJSON {
+		r.cut = nw.MinCut()
+	}
+	return r.write(w, opts)
+}
+
+// WriteResult writes s to w in the format of opts; FormatDimacs is as
+// Dimacs returns it.
+func (s *SolutionOf[T]) WriteResult(w io.Writer, opts ResultOptions) error {
+	r := &resultOf[T]{
+		ctx:        s.Ctx,
+		epsilon:    s.Epsilon,
+		flow:       s.Flow,
+		feasible:   s.Feasible,
+		optimal:    s.Optimal,
+		violations: s.Violations,
+		cut:        s.Cut,
+		stats:      s.Stats,
+		timings:    s.Timings,
+		arcs: func(yield func(ArcFlowOf[T]) bool) {
+			for _, a := range s.Arcs {
+				if !yield(a) {
+					return
+				}
+			}
+		},
+	}
+	return r.write(w, opts)
+}
+
+// resultOf is what WriteResult writes of a Network or a Solution.
+type resultOf[T Capacity] struct {
+	ctx               Context
+	epsilon           T
+	flow              T
+	feasible, optimal bool
+	violations        []string
+	cut               *CutOf[T]
+	stats             Statistics
+	timings           Timings
+	arcs              iter.Seq[ArcFlowOf[T]]
+}
+
+// zero reports whether flow is within the epsilon of r of 0.
+func (r *resultOf[T]) zero(flow T) bool {
+	return flow <= r.epsilon && flow >= -r.epsilon
+}
+
+func (r *resultOf[T]) write(w io.Writer, opts ResultOptions) error {
+	if opts.OmitArcs {
+		r.arcs = func(yield func(ArcFlowOf[T]) bool) {}
+	}
+	bw := bufio.NewWriterSize(w, 1<<16)
+	var err error
+	switch opts.Format {
+	case FormatDimacs:
+		err = r.writeDimacs(bw, opts)
+	case FormatJSON:
+		err = r.writeJSON(bw, opts)
+	case FormatCSV:
+		err = r.writeCSV(bw, opts)
+	default:
+		err = fmt.Errorf("unknown result format %v", opts.Format)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func (r *resultOf[T]) writeDimacs(bw *bufio.Writer, opts ResultOptions) error {
+	flowLine := opts.FlowLine
+	switch flowLine {
+	case 0:
+		flowLine = 'f'
+	case 'f', 'a':
+	default:
+		return fmt.Errorf("flow line type %q is not f or a", flowLine)
+	}
+
+	lines := resultComments(opts.Header, r.ctx)
+	lines = append(lines, checkComments(r.violations, r.feasible, r.optimal)...)
+	if r.optimal {
+		lines = append(lines, fmt.Sprintf("s %v", r.flow))
+	}
+	if r.ctx.DisplayCut && r.cut != nil {
+		lines = append(lines, r.cut.Dimacs()...)
+	}
+	if !opts.OmitArcs {
+		lines = append(lines, "c ", "c SRC DST FLOW")
+	}
+	for _, line := range lines {
+		bw.WriteString(line)
+		bw.WriteByte('\n')
+	}
+
+	b := make([]byte, 0, 64)
+	for a := range r.arcs {
+		if opts.OmitZeroFlow && r.zero(a.Flow) {
+			continue
+		}
+		b = append(b[:0], flowLine, ' ')
+		b = strconv.AppendUint(b, uint64(a.From), 10)
+		b = append(b, ' ')
+		b = strconv.AppendUint(b, uint64(a.To), 10)
+		b = append(b, ' ')
+		b = appendCapacity(b, a.Flow)
+		b = append(b, '\n')
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resultJSON is the FormatJSON object but for its arcs, which are
+// written one at a time after it.
+type resultJSON[T Capacity] struct {
+	Flow       T           `json:"flow"`
+	Feasible   bool        `json:"feasible"`
+	Optimal    bool        `json:"optimal"`
+	Violations []string    `json:"violations,omitempty"`
+	Config     Context     `json:"config"`
+	Stats      Statistics  `json:"stats"`
+	Timings    Timings     `json:"timings"`
+	Cut        *cutJSON[T] `json:"cut,omitempty"`
+}
+
+type cutJSON[T Capacity] struct {
+	SourceSet []uint `json:"sourceSet"`
+	Capacity  T      `json:"capacity"`
+}
+
+func (r *resultOf[T]) writeJSON(bw *bufio.Writer, opts ResultOptions) error {
+	head := resultJSON[T]{
+		Flow:       r.flow,
+		Feasible:   r.feasible,
+		Optimal:    r.optimal,
+		Violations: r.violations,
+		Config:     r.ctx,
+		Stats:      r.stats,
+		Timings:    r.timings,
+	}
+	if r.cut != nil {
+		head.Cut = &cutJSON[T]{SourceSet: r.cut.SourceSet, Capacity: r.cut.Capacity}
+		if head.Cut.SourceSet == nil {
+			head.Cut.SourceSet = []uint{}
+		}
+	}
+	j, err := json.Marshal(head)
+	if err != nil {
+		return err
+	}
+	bw.Write(j[:len(j)-1]) // without the closing brace
+	bw.WriteString(`,"arcs":[`)
+
+	b := make([]byte, 0, 128)
+	sep := ""
+	for a := range r.arcs {
+		if opts.OmitZeroFlow && r.zero(a.Flow) {
+			continue
+		}
+		b = append(b[:0], sep+`{"id":`...)
+		b = strconv.AppendUint(b, uint64(a.ID), 10)
+		b = append(b, `,"from":`...)
+		b = strconv.AppendUint(b, uint64(a.From), 10)
+		b = append(b, `,"to":`...)
+		b = strconv.AppendUint(b, uint64(a.To), 10)
+		b = append(b, `,"flow":`...)
+		b = appendCapacity(b, a.Flow)
+		b = append(b, `,"capacity":`...)
+		b = appendCapacity(b, a.Capacity)
+		b = append(b, '}')
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+		sep = ","
+	}
+	_, err = bw.WriteString("]}\n")
+	return err
+}
+
+func (r *resultOf[T]) writeCSV(bw *bufio.Writer, opts ResultOptions) error {
+	bw.WriteString("id,from,to,flow,capacity\n")
+	b := make([]byte, 0, 128)
+	for a := range r.arcs {
+		if opts.OmitZeroFlow && r.zero(a.Flow) {
+			continue
+		}
+		b = strconv.AppendUint(b[:0], uint64(a.ID), 10)
+		b = append(b, ',')
+		b = strconv.AppendUint(b, uint64(a.From), 10)
+		b = append(b, ',')
+		b = strconv.AppendUint(b, uint64(a.To), 10)
+		b = append(b, ',')
+		b = appendCapacity(b, a.Flow)
+		b = append(b, ',')
+		b = appendCapacity(b, a.Capacity)
+		b = append(b, '\n')
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resultComments returns the comment lines that start a result: the
+// header and the runtime configuration of ctx.
+func resultComments(header string, ctx Context) []string {
+	ret := []string{
+		"c " + header,
+		"c ",
+		"c Dimacs-format maximum flow result file",
+		"c generated by pseudo.go",
+		"c ",
+		"c Optimal flow using  Hochbaum's PseudoFlow algorithm",
+		"c ",
+		"c Runtime Configuration -"}
+
+	if ctx.LowestLabel {
+		ret = append(ret, "c Lowest label pseudoflow algorithm")
+	} else {
+		ret = append(ret, "c Highest label pseudoflow algorithm")
+	}
+	if ctx.FifoBucket {
+		ret = append(ret, "c Using FIFO buckets")
+	} else {
+		ret = append(ret, "c Using LIFO buckets")
+	}
+	return append(ret, "c ")
+}
+
+// checkComments returns the comment lines of the checks of a solution, as
+// checkOptimality of C source code, up to the "s" line if it is optimal.
+func checkComments(violations []string, feasible, optimal bool) []string {
+	var ret []string
+	for _, v := range violations {
+		ret = append(ret, "c "+v)
+	}
+	if feasible {
+		ret = append(ret, "c ", "c Solution checks as feasible")
+	}
+	if !optimal {
+		ret = append(ret, "c ", "c Flow is not optimal - max flow does not equal min cut")
+	} else {
+		ret = append(ret, "c ", "c Solution checks as optimal", "c Solution")
+	}
+	return ret
+}
@@ -0,0 +1,139 @@
+// solution.go - the typed result of solving a Network.
+
+package pseudo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// SolutionOf is the outcome of solving a NetworkOf[T].
+type SolutionOf[T Capacity] struct {
+	// Flow is the value of the flow into the sink.
+	Flow T
+	// Feasible reports whether the flow satisfies the capacity and
+	// flow balance constraints; if not, Violations describes each breach.
+	Feasible   bool
+	Violations []string
+	// Optimal reports whether Flow equals the capacity of the min cut.
+	Optimal bool
+	// Arcs holds the flow on each arc in ArcID order, the order of the
+	// input, so that Arcs[id] is the arc id; Dimacs lists them so.
+	Arcs []ArcFlowOf[T]
+	// Cut is the minimum cut; Dimacs lists its source set if Ctx.DisplayCut is set.
+	Cut     *CutOf[T]
+	Stats   Statistics
+	Timings Timings
+	// Ctx is the Context the Network was solved with, and Epsilon its
+	// Epsilon, within which WriteResult takes a flow to be 0.
+	Ctx     Context
+	Epsilon T
+}
+
+// Solution is the outcome of solving a Network.
+type Solution = SolutionOf[int64]
+
+// ArcFlowOf is the flow on an arc of a solved NetworkOf[T].
+type ArcFlowOf[T Capacity] struct {
+	ID       ArcID
+	From, To uint
+	Flow     T
+	Capacity T
+}
+
+// ArcFlow is the flow on an arc of a solved Network.
+type ArcFlow = ArcFlowOf[int64]
+
+// Timings are the durations of the processing steps of a solve; see TimerJSON.
+type Timings struct {
+	ReadDimacsFile, SimpleInitialization, FlowPhaseOne, RecoverFlow, Total time.Duration
+}
+
+// solution collects the Solution of nw after RecoverFlow.
+func (nw *NetworkOf[T]) solution() *SolutionOf[T] {
+	opt := nw.checkOptimality()
+	sol := &SolutionOf[T]{
+		Flow:       opt.flow,
+		Feasible:   opt.feasible,
+		Violations: opt.violations,
+		Optimal:    opt.optimal,
+		Arcs:       make([]ArcFlowOf[T], nw.numArcs),
+		Cut:        nw.MinCut(),
+		Stats:      nw.stats,
+		Timings:    nw.timings(),
+		Ctx:        nw.Ctx,
+		Epsilon:    nw.Epsilon,
+	}
+	for _, a := range nw.arcList {
+		sol.Arcs[a.id] = ArcFlowOf[T]{
+			ID:       a.id,
+			From:     a.from.number,
+			To:       a.to.number,
+			Flow:     a.flow,
+			Capacity: a.capacity,
+		}
+	}
+
+	return sol
+}
+
+// Arc returns the flow on arc id, reporting whether s has it; a solve
+// stopped by its context has no arcs.
+func (s *SolutionOf[T]) Arc(id ArcID) (ArcFlowOf[T], bool) {
+	if uint(id) >= uint(len(s.Arcs)) {
+		return ArcFlowOf[T]{}, false
+	}
+	return s.Arcs[id], true
+}
+
+// WriteDimacs writes the solution lines of s to w in the DIMACS max-flow
+// format, without the comment lines of Dimacs: the "s" line of the flow
+// value and an "f SRC DST FLOW" line for each arc in ArcID order, that of
+// the "a" lines of NetworkOf.WriteDimacs.
+func (s *SolutionOf[T]) WriteDimacs(w io.Writer) error {
+	bw := bufio.NewWriterSize(w, 1<<16)
+	b := appendCapacity(append(make([]byte, 0, 64), "s "...), s.Flow)
+	b = append(b, '\n')
+	if _, err := bw.Write(b); err != nil {
+		return err
+	}
+	for _, a := range s.Arcs {
+		b = append(b[:0], "f "...)
+		b = strconv.AppendUint(b, uint64(a.From), 10)
+		b = append(b, ' ')
+		b = strconv.AppendUint(b, uint64(a.To), 10)
+		b = append(b, ' ')
+		b = appendCapacity(b, a.Flow)
+		b = append(b, '\n')
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Dimacs returns the Solution as lines of Dimacs syntax, as described for Result.
+func (s *SolutionOf[T]) Dimacs(header string) []string {
+	// header and runtime config info
+	ret := resultComments(header, s.Ctx)
+
+	// add Solution - as checkOptimality of C source code
+	ret = append(ret, checkComments(s.Violations, s.Feasible, s.Optimal)...)
+	if s.Optimal {
+		ret = append(ret, fmt.Sprintf("s %v", s.Flow))
+	}
+
+	// add source set of min cut - as displayCut of C source code
+	if s.Ctx.DisplayCut {
+		ret = append(ret, s.Cut.Dimacs()...)
+	}
+
+	// add flows
+	ret = append(ret, "c ", "c SRC DST FLOW")
+	ret = append(ret, displayFlow(s.Arcs)...)
+
+	return ret
+}
@@ -0,0 +1,645 @@
+// update.go - change the arcs and nodes of a solved Network and solve it again.
+
+package pseudo
+
+import (
+	"context"
+	"fmt"
+)
+
+// UpdateCapacity sets the capacity of arc id. On a Network that has been
+// solved, by Solve, SolveMinCut or FlowPhaseOne, the pseudoflow of the last
+// solve is adjusted to the new capacity: the flow on the arc is cut back, or
+// kept saturated, and the difference is added to the excess or deficit of its
+// nodes, whose trees are split where a tree arc reaches a bound. The next
+// Solve or SolveMinCut then relabels the normalized tree and continues
+// FlowPhaseOne from it, rather than from SimpleInitialization, so that only
+// the excess and deficits made by the changes have to be moved. Should the
+// continued phase one not end in a minimum cut, the network is solved again
+// from the start, as counted by Statistics.NumRestarts.
+//
+// The state of FlowPhaseOne that RecoverFlow overwrites is kept only if
+// KeepPhaseOne is set, as the first change does, so unless it was set before
+// the Solve the first change has the next solve start over; from then on each
+// solve continues from the last. A continued solve saves the pushes and
+// relabels of FlowPhaseOne, not its passes over the network: keeping and
+// restoring the phase one state, and checking that FlowPhaseOne ended in a
+// minimum cut, each go over every node and arc.
+func (nw *NetworkOf[T]) UpdateCapacity(id ArcID, capacity T) error {
+	if uint(id) >= uint(len(nw.arcs)) {
+		return fmt.Errorf("no arc %d", id)
+	}
+	a := nw.arcs[id]
+	if capacity != capacity {
+		return fmt.Errorf("arc %d (%d, %d): capacity NaN", id, a.from.number, a.to.number)
+	}
+	if capacity < 0 {
+		return fmt.Errorf("arc %d (%d, %d): negative capacity %v", id, a.from.number, a.to.number, capacity)
+	}
+	if capacity > a.capacity && nw.total > maxCapacity[T]()-(capacity-a.capacity) {
+		return fmt.Errorf("arc %d (%d, %d): total arc capacity overflows %T", id, a.from.number, a.to.number, capacity)
+	}
+	nw.total += capacity - a.capacity
+	a.capacity = capacity
+	if !nw.initialized || !nw.beginUpdate() {
+		return nil
+	}
+	nw.touchArc(a)
+
+	from, to := a.from.number, a.to.number
+	switch {
+	case nw.source == to || nw.sink == from || from == to:
+		// not used by build
+	case from == nw.source || to == nw.sink:
+		// out of the source or into the sink: saturated by SimpleInitialization
+		nw.setFlow(a, capacity)
+	case nw.treeArc(a):
+		if a.flow > capacity {
+			nw.setFlow(a, capacity)
+		}
+	case a.direction == 0:
+		// saturated, in the list of a.to
+		nw.setFlow(a, capacity)
+	}
+
+	return nil
+}
+
+// treeArc reports whether a joins a node to its parent.
+func (nw *NetworkOf[T]) treeArc(a *arc[T]) bool {
+	return (a.from.parent == a.to && a.from.arcToParent == a) ||
+		(a.to.parent == a.from && a.to.arcToParent == a)
+}
+
+// setFlow sets the flow on a and moves the difference to the excess of its
+// nodes; the excess of the source and the sink is not kept.
+func (nw *NetworkOf[T]) setFlow(a *arc[T], flow T) {
+	delta := flow - a.flow
+	a.flow = flow
+	switch {
+	case delta > 0:
+		nw.changeExcess(a.to, delta)
+		nw.changeExcess(a.from, -delta)
+	case delta < 0:
+		nw.changeExcess(a.from, -delta)
+		nw.changeExcess(a.to, delta)
+	}
+}
+
+func (nw *NetworkOf[T]) changeExcess(n *node[T], delta T) {
+	if n.number == nw.source || n.number == nw.sink {
+		return
+	}
+	nw.touch(n)
+	if delta > 0 {
+		nw.addExcess(n, delta)
+	} else {
+		nw.addDeficit(n, -delta)
+	}
+}
+
+// addDeficit takes delta > 0 from the excess of n; the deficit of a non-root
+// is pulled up to its root. Buckets are left to relabel.
+func (nw *NetworkOf[T]) addDeficit(n *node[T], delta T) {
+	n.excess -= delta
+	if n.parent != nil {
+		nw.pullDeficit(n)
+	}
+}
+
+// pullDeficit is pushExcess for a deficit: the flow on each arc to the parent
+// is changed to move the deficit of n up the tree. Where an arc reaches a
+// bound first the child is split off as a weak root, and the arc goes in its
+// list as it is residual from the child.
+func (nw *NetworkOf[T]) pullDeficit(n *node[T]) {
+	var current, parent *node[T]
+	var a *arc[T]
+	var move T
+
+	for current = n; nw.negative(current.excess) && current.parent != nil; current = parent {
+		parent = current.parent
+		a = current.arcToParent
+
+		// move is how far the flow on a can go towards current
+		if a.direction > 0 {
+			move = a.flow
+		} else {
+			move = a.capacity - a.flow
+		}
+		if !nw.positive(-current.excess - move) {
+			move = -current.excess
+		} else {
+			current.addOutOfTreeNode(a)
+			parent.breakRelationship(current)
+			nw.touch(current)
+		}
+		if a.direction > 0 {
+			a.flow -= move
+		} else {
+			a.flow += move
+		}
+		current.excess += move
+		parent.excess -= move
+	}
+}
+
+// changes are what UpdateCapacity, AddArc and AddNode have done to the
+// normalized tree since the last FlowPhaseOne, for relabel.
+type changes[T Capacity] struct {
+	nodes []*node[T] // nodes whose excess or tree changed
+	arcs  []*arc[T]  // arcs that may be residual in a new direction
+	// the strong labels before the first change, which pushExcess may move,
+	// widened by touch
+	lowestStrongLabel, highestStrongLabel uint
+}
+
+// touch records a change to the excess or the tree of n, and touchArc a change
+// to a, for relabel. The nodes on the path from n to its root are recorded
+// too, as pushExcess may split roots off it.
+func (nw *NetworkOf[T]) touch(n *node[T]) {
+	nw.startChanges()
+	ch := nw.changed
+	for ; n != nil; n = n.parent {
+		ch.nodes = append(ch.nodes, n)
+		if n.label < nw.numNodes {
+			ch.lowestStrongLabel = min(ch.lowestStrongLabel, n.label)
+			ch.highestStrongLabel = max(ch.highestStrongLabel, n.label)
+		}
+	}
+}
+
+func (nw *NetworkOf[T]) touchArc(a *arc[T]) {
+	nw.startChanges()
+	nw.changed.arcs = append(nw.changed.arcs, a)
+}
+
+func (nw *NetworkOf[T]) startChanges() {
+	if nw.changed == nil {
+		nw.changed = &changes[T]{lowestStrongLabel: nw.lowestStrongLabel, highestStrongLabel: nw.highestStrongLabel}
+	}
+}
+
+// relabel readies the normalized tree left by the changes of UpdateCapacity,
+// AddArc and AddNode for FlowPhaseOne to continue from it. The labels of the
+// last solve stay valid, no node having a residual arc to a node more than one
+// label below it, but where the changes made new residual arcs; and they
+// leave deficits that the excess elsewhere must be able to reach. So the trees
+// left with a deficit drop to label 0, and the tail of a new residual arc to
+// one above its head. A node that drops takes the ancestors above it along,
+// and then the nodes with residual arcs into it that are now too high, and so
+// on. Only the roots of the trees touched by the changes or dropped are
+// re-bucketed, and only the buckets they were or are in are cleaned of the
+// roots that are no longer strong.
+func (nw *NetworkOf[T]) relabel() {
+	ch := nw.changed
+	nw.changed = nil
+	if ch == nil {
+		return
+	}
+	numNodes := nw.numNodes
+	source, sink := nw.adjacencyList[nw.source-1], nw.adjacencyList[nw.sink-1]
+	incident := nw.incidence()
+
+	var roots, dropped []*node[T]
+	var buckets []uint
+	bucket := make(map[uint]bool)
+	addBucket := func(label uint) {
+		if label < numNodes && !bucket[label] {
+			bucket[label] = true
+			buckets = append(buckets, label)
+		}
+	}
+	// drop sets the label of n and its ancestors that are higher to label.
+	drop := func(n *node[T], label uint) {
+		for ; n != nil && n.label > label; n = n.parent {
+			if n.parent == nil {
+				addBucket(n.label)
+				roots = append(roots, n)
+			}
+			if n.label < numNodes {
+				nw.labelCount[n.label]--
+			}
+			nw.labelCount[label]++
+			n.label = label
+			n.nextArc = 0
+			dropped = append(dropped, n)
+		}
+	}
+
+	// the roots of the touched trees are touched too, as touch records the
+	// path to the root and the changes merge no trees
+	for _, n := range ch.nodes {
+		if n == source || n == sink {
+			continue
+		}
+		n.nextArc = 0
+		if n.parent != nil {
+			continue
+		}
+		r := n
+		roots = append(roots, r)
+		if !nw.negative(r.excess) {
+			continue
+		}
+		// a tree with a deficit drops to 0
+		stack := []*node[T]{r}
+		for len(stack) > 0 {
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			drop(current, 0)
+			for c := current.childList; c != nil; c = c.next {
+				stack = append(stack, c)
+			}
+		}
+	}
+	for _, a := range ch.arcs {
+		if a.from == source || a.from == sink || a.to == source || a.to == sink || a.from == a.to || nw.treeArc(a) {
+			continue
+		}
+		a.from.nextArc = 0
+		a.to.nextArc = 0
+		if a.flow < a.capacity && a.from.label > a.to.label+1 {
+			drop(a.from, a.to.label+1)
+		}
+		if a.flow > 0 && a.to.label > a.from.label+1 {
+			drop(a.to, a.from.label+1)
+		}
+	}
+	for k := 0; k < len(dropped); k++ {
+		current := dropped[k]
+		for _, i := range incident[current.number-1] {
+			a := nw.arcs[i]
+			other, residual := a.from, a.flow < a.capacity
+			if other == current {
+				other, residual = a.to, a.flow > 0
+			}
+			if other == source || other == sink || other == current || !residual || nw.treeArc(a) {
+				continue
+			}
+			// the arc may be the current arc of other again
+			other.nextArc = 0
+			if other.label > current.label+1 {
+				drop(other, current.label+1)
+			}
+		}
+	}
+
+	for _, r := range roots {
+		addBucket(r.label)
+	}
+	// clean the buckets and put the strong roots in them once
+	strong := make(map[*node[T]]bool)
+	lowest, highest := ch.lowestStrongLabel, ch.highestStrongLabel
+	add := func(n *node[T]) {
+		if n.parent != nil || !nw.positive(n.excess) || n.label >= numNodes || strong[n] {
+			return
+		}
+		strong[n] = true
+		if n.label == 0 {
+			// as getLowestStrongRoot does; the highest label never looks there
+			nw.raiseRoot(n)
+		}
+		nw.addToStrongBucket(n, nw.strongRoots[n.label])
+		lowest = min(lowest, n.label)
+		highest = max(highest, n.label)
+	}
+	for _, label := range buckets {
+		for n := nw.strongRoots[label].start; n != nil; n = n.next {
+			roots = append(roots, n)
+			nw.numStrongRoots--
+		}
+		*nw.strongRoots[label] = root[T]{}
+	}
+	for _, r := range roots {
+		add(r)
+	}
+	if nw.Ctx.LowestLabel {
+		nw.lowestStrongLabel = lowest
+	} else {
+		nw.highestStrongLabel = highest
+	}
+}
+
+// incidence returns the arcs at each node, by index into nw.arcs. They are
+// found once, when relabel first needs them, and kept up to date by addArc
+// and AddNode.
+func (nw *NetworkOf[T]) incidence() [][]uint32 {
+	if nw.incident != nil {
+		return nw.incident
+	}
+	count := make([]uint, nw.numNodes)
+	for _, a := range nw.arcs {
+		count[a.from.number-1]++
+		count[a.to.number-1]++
+	}
+	nw.incident = make([][]uint32, nw.numNodes)
+	for i, c := range count {
+		nw.incident[i] = make([]uint32, 0, c)
+	}
+	for i, a := range nw.arcs {
+		nw.incident[a.from.number-1] = append(nw.incident[a.from.number-1], uint32(i))
+		nw.incident[a.to.number-1] = append(nw.incident[a.to.number-1], uint32(i))
+	}
+	return nw.incident
+}
+
+// phaseOneState is the part of the state left by FlowPhaseOne that
+// RecoverFlow changes, kept so that the Network can be updated and solved again.
+type phaseOneState[T Capacity] struct {
+	flow            []T
+	excess          []T
+	numberOutOfTree []uint
+	nextArc         []uint
+	outOfTree       []*arc[T] // the lists of all nodes end to end
+}
+
+// savePhaseOne keeps the state of nw that RecoverFlow changes.
+func (nw *NetworkOf[T]) savePhaseOne() {
+	s := &phaseOneState[T]{
+		flow:            make([]T, len(nw.arcs)),
+		excess:          make([]T, nw.numNodes),
+		numberOutOfTree: make([]uint, nw.numNodes),
+		nextArc:         make([]uint, nw.numNodes),
+	}
+	for i, a := range nw.arcs {
+		s.flow[i] = a.flow
+	}
+	for i, n := range nw.adjacencyList {
+		s.excess[i] = n.excess
+		s.numberOutOfTree[i] = n.numberOutOfTree
+		s.nextArc[i] = n.nextArc
+		s.outOfTree = append(s.outOfTree, n.outOfTree[:n.numberOutOfTree]...)
+	}
+	nw.saved = s
+}
+
+// restorePhaseOne undoes RecoverFlow, if it has run since FlowPhaseOne.
+func (nw *NetworkOf[T]) restorePhaseOne() {
+	s := nw.saved
+	if s == nil {
+		return
+	}
+	nw.recovered = false
+	nw.solved = false
+	for i, a := range nw.arcs {
+		a.flow = s.flow[i]
+	}
+	var k uint
+	for i, n := range nw.adjacencyList {
+		n.excess = s.excess[i]
+		n.numberOutOfTree = s.numberOutOfTree[i]
+		n.nextArc = s.nextArc[i]
+		n.visited = 0
+		copy(n.outOfTree, s.outOfTree[k:k+n.numberOutOfTree])
+		k += n.numberOutOfTree
+	}
+	nw.saved = nil
+}
+
+// beginUpdate readies an initialized nw for a change by UpdateCapacity,
+// AddArc or AddNode, and sets KeepPhaseOne. It reports whether the state of the last FlowPhaseOne
+// is there to change; if RecoverFlow has not kept it, nw is reset to solve
+// from SimpleInitialization.
+func (nw *NetworkOf[T]) beginUpdate() bool {
+	nw.KeepPhaseOne = true
+	if nw.recovered && nw.saved == nil {
+		nw.logger().Debug("phase one state not kept, solving from the start")
+		nw.reset()
+		return false
+	}
+	nw.restorePhaseOne()
+	return true
+}
+
+// startPhaseOne readies nw for FlowPhaseOne. A new Network is initialized;
+// one that has been solved is returned to its phase one state and relabeled,
+// so that FlowPhaseOne continues from the normalized tree left by UpdateCapacity.
+func (nw *NetworkOf[T]) startPhaseOne() error {
+	if nw.initialized && nw.recovered && nw.saved == nil {
+		// solved again without a change, or after RecoverFlow was stopped
+		nw.reset()
+	}
+	nw.warm = nw.initialized
+	if !nw.warm {
+		return nw.SimpleInitialization()
+	}
+
+	nw.startPhase(PhaseInitialization)
+	if nw.changed != nil {
+		// not a solve resumed after it was stopped
+		nw.stats.NumWarmStarts++
+	}
+	nw.restorePhaseOne()
+	nw.relabel()
+	nw.finishPhase(PhaseInitialization)
+	return nil
+}
+
+// runPhaseOne runs FlowPhaseOne after startPhaseOne, checking ctx as
+// flowPhaseOne does. When it continued from a previous solve and did not end
+// in a minimum cut, nw is solved again from SimpleInitialization.
+func (nw *NetworkOf[T]) runPhaseOne(ctx context.Context) error {
+	if err := nw.flowPhaseOne(ctx); err != nil {
+		return err
+	}
+	if !nw.warm || nw.validCut() {
+		return nil
+	}
+	nw.stats.NumRestarts++
+	nw.logger().Debug("continued phase one ended in no minimum cut, solving from the start")
+
+	nw.reset()
+	if err := nw.SimpleInitialization(); err != nil {
+		return err
+	}
+	return nw.flowPhaseOne(ctx)
+}
+
+// validCut reports whether the gap left by FlowPhaseOne is a minimum cut that
+// RecoverFlow can turn into a maximum flow: every root on the source side has
+// no deficit and every root on the sink side no excess, the arcs across the
+// cut are saturated and those back across it carry no flow.
+// Trees of mixed labels, which a continued FlowPhaseOne can lift over the
+// gap apart from their neighbours, may fail it.
+func (nw *NetworkOf[T]) validCut() bool {
+	gap := nw.gap()
+	for _, n := range nw.adjacencyList {
+		if n.parent != nil || n.number == nw.source || n.number == nw.sink {
+			continue
+		}
+		if n.label >= gap && nw.negative(n.excess) {
+			return false
+		}
+		if n.label < gap && nw.positive(n.excess) {
+			return false
+		}
+	}
+	for _, a := range nw.arcs {
+		from, to := a.from.number, a.to.number
+		if from == nw.source || from == nw.sink || to == nw.source || to == nw.sink || from == to {
+			continue
+		}
+		fromSource, toSource := a.from.label >= gap, a.to.label >= gap
+		if fromSource && !toSource && !nw.zero(a.capacity-a.flow) {
+			return false
+		}
+		if !fromSource && toSource && !nw.zero(a.flow) {
+			return false
+		}
+	}
+	return true
+}
+
+// reset returns nw to its state before SimpleInitialization, keeping the
+// arcs and their capacities.
+func (nw *NetworkOf[T]) reset() {
+	for _, n := range nw.adjacencyList {
+		*n = node[T]{number: n.number, numAdjacent: n.numAdjacent}
+	}
+	for _, a := range nw.arcs {
+		a.flow = 0
+		a.direction = 1
+	}
+	for i := range nw.labelCount {
+		nw.labelCount[i] = 0
+	}
+	for _, r := range nw.strongRoots {
+		*r = root[T]{}
+	}
+	nw.lowestStrongLabel = 1
+	nw.highestStrongLabel = 1
+	nw.numStrongRoots = 0
+	nw.saved = nil
+	nw.recovered = false
+	nw.solved = false
+	nw.changed = nil
+	nw.built = false
+	nw.initialized = false
+}
+
+// cancelDeficits takes back the flow that leaves the nodes with a deficit
+// once the arcs into the sink have been cut back, for RecoverFlow. After a
+// solve from SimpleInitialization a deficit never outgrows the flow into the
+// sink from its own node, but after UpdateCapacity it may. Each remaining
+// deficit is cancelled along paths of arcs with flow, which lead from it to
+// the sink or to an excess.
+func (nw *NetworkOf[T]) cancelDeficits() {
+	var deficits []*node[T]
+	for _, n := range nw.adjacencyList {
+		if n.number != nw.source && n.number != nw.sink && nw.negative(n.excess) {
+			deficits = append(deficits, n)
+		}
+	}
+	if len(deficits) == 0 {
+		return
+	}
+
+	// the arcs out of each node, by index into nw.arcs
+	start := make([]uint, nw.numNodes+1)
+	for _, a := range nw.arcs {
+		start[a.from.number]++
+	}
+	for i := uint(1); i <= nw.numNodes; i++ {
+		start[i] += start[i-1]
+	}
+	out := make([]uint32, start[nw.numNodes])
+	next := make([]uint, nw.numNodes)
+	copy(next, start)
+	for i, a := range nw.arcs {
+		out[next[a.from.number-1]] = uint32(i)
+		next[a.from.number-1]++
+	}
+
+	visited := make([]uint, nw.numNodes)
+	var search uint
+	var path []*arc[T]
+	for _, n := range deficits {
+		for nw.negative(n.excess) {
+			// depth first search for a path of arcs with flow
+			search++
+			path = path[:0]
+			copy(next, start)
+			current := n
+			visited[n.number-1] = search
+			for current.number != nw.sink && (current == n || !nw.positive(current.excess)) {
+				var a *arc[T]
+				for ; next[current.number-1] < start[current.number]; next[current.number-1]++ {
+					b := nw.arcs[out[next[current.number-1]]]
+					if nw.positive(b.flow) && b.to.number != nw.source && visited[b.to.number-1] != search {
+						a = b
+						break
+					}
+				}
+				if a == nil {
+					if len(path) == 0 {
+						break
+					}
+					current = path[len(path)-1].from
+					path = path[:len(path)-1]
+					next[current.number-1]++
+					continue
+				}
+				visited[a.to.number-1] = search
+				path = append(path, a)
+				current = a.to
+			}
+			if len(path) == 0 {
+				// the flow is not a pseudoflow; leave the deficit to checkOptimality
+				break
+			}
+
+			move := -n.excess
+			if current.number != nw.sink && current.excess < move {
+				move = current.excess
+			}
+			for _, a := range path {
+				if a.flow < move {
+					move = a.flow
+				}
+			}
+			for _, a := range path {
+				a.flow -= move
+			}
+			n.excess += move
+			if current.number != nw.sink {
+				current.excess -= move
+			}
+		}
+	}
+}
+
+// insertArc places a, just added by AddArc to an initialized nw, as build and
+// SimpleInitialization would have: in the list of the source or the sink and
+// saturated if it runs out of one or into the other, and otherwise in the list
+// of a.from. The flow out of the source or into the sink changes the excess of
+// the other node as UpdateCapacity would.
+func (nw *NetworkOf[T]) insertArc(a *arc[T]) {
+	if a.capacity != a.capacity || a.capacity < 0 || nw.total > maxCapacity[T]()-a.capacity {
+		nw.reset()
+		return
+	}
+	nw.total += a.capacity
+	nw.arcList = append(nw.arcList, a)
+	nw.numArcs++
+	nw.built = true
+	// RecoverFlow may move an arc to the list of either node
+	a.from.outOfTree = append(a.from.outOfTree, nil)
+	a.to.outOfTree = append(a.to.outOfTree, nil)
+
+	from, to := a.from.number, a.to.number
+	switch {
+	case nw.source == to || nw.sink == from || from == to:
+		// not used by build
+	case from == nw.source && to == nw.sink:
+		a.flow = a.capacity
+	case from == nw.source:
+		a.from.addOutOfTreeNode(a)
+		nw.setFlow(a, a.capacity)
+	case to == nw.sink:
+		a.to.addOutOfTreeNode(a)
+		nw.setFlow(a, a.capacity)
+	default:
+		a.from.addOutOfTreeNode(a)
+		nw.touchArc(a)
+	}
+}